@@ -0,0 +1,65 @@
+package pbast
+
+import "reflect"
+
+// Clone returns a deep copy of the file.
+// Rewrite passes modify a file in place, so clone it first to keep the original.
+// Nodes shared in the file, e.g. a message nested in itself, are shared in the copy.
+func (f *File) Clone() *File {
+	return deepCopy(reflect.ValueOf(f), map[copiedPointer]reflect.Value{}).Interface().(*File)
+}
+
+type copiedPointer struct {
+	t reflect.Type
+	p uintptr
+}
+
+func deepCopy(v reflect.Value, copied map[copiedPointer]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := copiedPointer{v.Type(), v.Pointer()}
+		if c, ok := copied[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		copied[key] = c
+		c.Elem().Set(deepCopy(v.Elem(), copied))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(deepCopy(v.Field(i), copied))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMap(v.Type())
+		for _, k := range v.MapKeys() {
+			c.SetMapIndex(deepCopy(k, copied), deepCopy(v.MapIndex(k), copied))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), copied))
+		return c
+	default:
+		return v
+	}
+}
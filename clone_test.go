@@ -0,0 +1,36 @@
+package pbast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	node := NewMessage("Node").
+		AddField(NewMessageField(NewMessage("Node"), "next", 1))
+	node.AddMessage(node)
+
+	f := NewFile("org.foo").
+		AddImport(NewImport("a.proto")).
+		AddMessage(NewMessage("Human").
+			AddField(NewMessageField(String, "name", 1).
+				AddOption(NewFieldOption("deprecated", "true")))).
+		AddMessage(node).
+		AddService(NewService("HumanService").
+			AddRPC(NewRPC("GetHuman", NewReturnType("Human"), NewReturnType("Human"))))
+
+	c := f.Clone()
+	if !reflect.DeepEqual(c, f) {
+		t.Fatalf("got %+v, want %+v", c, f)
+	}
+
+	c.Messages[0].Fields[0].Options[0].Value = "false"
+	c.Services[0].RPCs[0].Input.Name = "Node"
+	if f.Messages[0].Fields[0].Options[0].Value != "true" || f.Services[0].RPCs[0].Input.Name != "Human" {
+		t.Errorf("modifying the copy changed the original")
+	}
+
+	if c.Messages[1] == node || c.Messages[1].Messages[0] != c.Messages[1] {
+		t.Errorf("got %p nested in %p, want the copy nested in itself", c.Messages[1].Messages[0], c.Messages[1])
+	}
+}
@@ -1,9 +1,13 @@
 package pbast
 
 type Enum struct {
-	Name    string
-	Comment Comment
-	Fields  []*EnumField
+	Name    string       `json:"name"`
+	Comment Comment      `json:"comment,omitempty"`
+	Fields  []*EnumField `json:"fields,omitempty"`
+	// Reserved holds the value numbers and ReservedNames the value names
+	// which can't be used, typically those of removed values
+	Reserved      []*ReservedRange `json:"reserved,omitempty"`
+	ReservedNames []string         `json:"reservedNames,omitempty"`
 }
 
 func NewEnum(name string) *Enum {
@@ -20,6 +24,32 @@ func (e *Enum) AddField(f *EnumField) *Enum {
 	return e
 }
 
+func (e *Enum) AddReservedRange(r *ReservedRange) *Enum {
+	if r == nil {
+		return e
+	}
+	e.Reserved = append(e.Reserved, r)
+	return e
+}
+
+func (e *Enum) AddReservedName(name string) *Enum {
+	if name == "" {
+		return e
+	}
+	e.ReservedNames = append(e.ReservedNames, name)
+	return e
+}
+
+// IsReserved reports whether the value number is reserved in the enum
+func (e *Enum) IsReserved(index int) bool {
+	for _, r := range e.Reserved {
+		if r.Start <= index && index <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Enum) identifiers() stringSet {
 	if len(e.Fields) == 0 {
 		return newStringSet()
@@ -33,14 +63,14 @@ func (e *Enum) identifiers() stringSet {
 }
 
 type EnumField struct {
-	Name    string
-	Index   int
-	Options []*EnumValueOption
+	Name    string             `json:"name"`
+	Index   int                `json:"index"`
+	Options []*EnumValueOption `json:"options,omitempty"`
 }
 
 type EnumValueOption struct {
-	Name  string
-	Value string
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 func NewEnumField(name string, index int) *EnumField {
@@ -0,0 +1,38 @@
+package pbast
+
+// MaxFieldNumber is the largest field number, printed as "max" in ranges
+const MaxFieldNumber = 536870911
+
+// ExtensionRange is a range of field numbers for extensions including both ends.
+// Extension ranges are proto2 only.
+type ExtensionRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func NewExtensionRange(start, end int) *ExtensionRange {
+	return &ExtensionRange{
+		Start: start,
+		End:   end,
+	}
+}
+
+type Extend struct {
+	Type    string          `json:"type"`
+	Comment Comment         `json:"comment,omitempty"`
+	Fields  []*MessageField `json:"fields,omitempty"`
+}
+
+func NewExtend(t Type) *Extend {
+	return &Extend{
+		Type: t.TypeName(),
+	}
+}
+
+func (e *Extend) AddField(f *MessageField) *Extend {
+	if f == nil {
+		return e
+	}
+	e.Fields = append(e.Fields, f)
+	return e
+}
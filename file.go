@@ -1,14 +1,17 @@
 package pbast
 
 type File struct {
-	Syntax   Syntax
-	Package  Package
-	Comment  Comment
-	Imports  []*Import
-	Options  []*Option
-	Messages []*Message
-	Enums    []*Enum
-	Services []*Service
+	Syntax   Syntax     `json:"syntax"`
+	Package  Package    `json:"package,omitempty"`
+	Comment  Comment    `json:"comment,omitempty"`
+	Imports  []*Import  `json:"imports,omitempty"`
+	Options  []*Option  `json:"options,omitempty"`
+	Messages []*Message `json:"messages,omitempty"`
+	Enums    []*Enum    `json:"enums,omitempty"`
+	Extends  []*Extend  `json:"extends,omitempty"`
+	Services []*Service `json:"services,omitempty"`
+	// Renames records the declarations renamed by rewrite passes
+	Renames RenameTable `json:"renames,omitempty"`
 }
 
 func NewFile(p Package) *File {
@@ -47,6 +50,14 @@ func (f *File) AddEnum(e *Enum) *File {
 	return f
 }
 
+func (f *File) AddExtend(e *Extend) *File {
+	if e == nil {
+		return f
+	}
+	f.Extends = append(f.Extends, e)
+	return f
+}
+
 func (f *File) AddService(s *Service) *File {
 	if s == nil {
 		return f
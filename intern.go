@@ -0,0 +1,55 @@
+package pbast
+
+import "reflect"
+
+// Intern makes equal strings in the file, e.g. names such as "config" or
+// "state" repeated in large models, share their memory so that the copies
+// can be garbage collected. The file is modified in place.
+func (f *File) Intern() {
+	intern(reflect.ValueOf(f), map[string]string{}, map[uintptr]bool{})
+}
+
+func intern(v reflect.Value, strs map[string]string, visited map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		intern(v.Elem(), strs, visited)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			intern(v.Field(i), strs, visited)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			intern(v.Index(i), strs, visited)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(k))
+			intern(value, strs, visited)
+			v.SetMapIndex(k, reflect.Value{})
+			key := reflect.New(v.Type().Key()).Elem()
+			key.Set(k)
+			intern(key, strs, visited)
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		intern(elem, strs, visited)
+		v.Set(elem)
+	case reflect.String:
+		s := v.String()
+		if interned, ok := strs[s]; ok {
+			v.SetString(interned)
+		} else {
+			strs[s] = s
+		}
+	}
+}
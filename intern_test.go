@@ -0,0 +1,40 @@
+package pbast
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestIntern(t *testing.T) {
+	// names built at run time don't share memory
+	name := func() string { return strings.Repeat("config", 1) + "_" }
+	f := NewFile("org.foo").
+		AddMessage(NewMessage("Interface").
+			AddField(NewMessageField(String, name(), 1))).
+		AddMessage(NewMessage("Vlan").
+			AddField(NewMessageField(String, name(), 1)))
+	f.Renames = RenameTable{name(): name()}
+	expected := f.Clone()
+
+	f.Intern()
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+
+	p1 := stringData(f.Messages[0].Fields[0].Name)
+	p2 := stringData(f.Messages[1].Fields[0].Name)
+	if p1 != p2 {
+		t.Error("equal names don't share memory")
+	}
+	for old := range f.Renames {
+		if stringData(old) != p1 {
+			t.Error("keys of the rename table don't share memory")
+		}
+	}
+}
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
@@ -0,0 +1,407 @@
+package pbast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InvariantError reports the structural problems found in a file
+type InvariantError struct {
+	Problems []string
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("invalid file: %s", strings.Join(e.Problems, "; "))
+}
+
+// CheckInvariants verifies structural invariants of the file: names are unique
+// in each scope, field and enum value numbers are valid, and type references
+// resolve to builtin types or types declared in the file. When the file has
+// imports, references to the types not declared in the file are assumed to be
+// satisfied by them, including the types of the same package, unless the file
+// declares the leading component of the reference, which shadows imported types.
+// Enum values must be unique in the scope enclosing the enum, and extension and
+// reserved ranges must not overlap fields or each other.
+// It returns an *InvariantError listing all problems found.
+func CheckInvariants(f *File) error {
+	c := &invariantChecker{
+		file:  f,
+		types: newStringSet(),
+	}
+	c.collectTypes()
+	c.checkFile()
+
+	if len(c.problems) > 0 {
+		return &InvariantError{Problems: c.problems}
+	}
+	return nil
+}
+
+// field numbers reserved for the protobuf implementation
+const (
+	firstReservedFieldNumber = 19000
+	lastReservedFieldNumber  = 19999
+)
+
+type invariantChecker struct {
+	file *File
+	// types holds the full names of types declared in the file
+	types    stringSet
+	problems []string
+}
+
+func (c *invariantChecker) report(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (c *invariantChecker) collectTypes() {
+	for _, e := range c.file.Enums {
+		c.types.add(e.Name)
+	}
+	WalkMessages(c.file, func(scope string, m *Message) {
+		name := qualify(scope, m.Name)
+		c.types.add(name)
+		for _, e := range m.Enums {
+			c.types.add(qualify(name, e.Name))
+		}
+	})
+}
+
+// unique reports names declared more than once in the scope
+func (c *invariantChecker) unique(scope string, names []string) {
+	seen := newStringSet()
+	for _, name := range names {
+		if seen.contains(name) {
+			c.report("%s is declared more than once", qualify(scope, name))
+		}
+		seen.add(name)
+	}
+}
+
+func (c *invariantChecker) checkFile() {
+	f := c.file
+
+	var names []string
+	for _, m := range f.Messages {
+		names = append(names, m.Name)
+	}
+	for _, e := range f.Enums {
+		names = append(names, e.Name)
+	}
+	for _, s := range f.Services {
+		names = append(names, s.Name)
+	}
+	c.unique("", append(names, enumValueNames(f.Enums)...))
+
+	for _, name := range SelfNestedMessages(f) {
+		c.report("%s: message nested in itself", name)
+	}
+	WalkMessages(f, c.checkMessage)
+	for _, e := range f.Enums {
+		c.checkEnum("", e)
+	}
+	for _, e := range f.Extends {
+		c.checkExtend("", e)
+	}
+	for _, s := range f.Services {
+		rpcs := []string{}
+		for _, r := range s.RPCs {
+			rpcs = append(rpcs, r.Name)
+			for _, t := range []*ReturnType{r.Input, r.Output} {
+				if t == nil {
+					c.report("RPC %s.%s lacks input or output", s.Name, r.Name)
+					continue
+				}
+				c.checkReference("", t.Name)
+			}
+		}
+		c.unique(s.Name, rpcs)
+	}
+}
+
+func (c *invariantChecker) checkMessage(scope string, m *Message) {
+	name := qualify(scope, m.Name)
+
+	var names []string
+	for _, f := range m.Fields {
+		names = append(names, f.Name)
+		c.checkFieldNumber(name, f.Name, f.Index)
+		c.checkReference(name, f.Type)
+	}
+	for _, f := range m.Maps {
+		names = append(names, f.Name)
+		c.checkFieldNumber(name, f.Name, f.Index)
+		c.checkReference(name, f.ValueType)
+		if err := f.Validate(); err != nil {
+			c.report("%s: %v", name, err)
+		}
+	}
+	for _, o := range m.OneOfs {
+		names = append(names, o.Name)
+		for _, f := range o.Fields {
+			names = append(names, f.Name)
+			c.checkFieldNumber(name, f.Name, f.Index)
+			c.checkReference(name, f.Type)
+		}
+	}
+	for _, g := range m.Groups {
+		names = append(names, g.Name)
+		c.checkFieldNumber(name, g.Name, g.Index)
+
+		group := qualify(name, g.Name)
+		var fields []string
+		for _, f := range g.Fields {
+			fields = append(fields, f.Name)
+			c.checkFieldNumber(group, f.Name, f.Index)
+			c.checkReference(name, f.Type)
+		}
+		c.unique(group, fields)
+	}
+	for _, n := range m.Messages {
+		names = append(names, n.Name)
+	}
+	for _, e := range m.Enums {
+		names = append(names, e.Name)
+	}
+	c.unique(name, append(names, enumValueNames(m.Enums)...))
+
+	if err := m.ValidateFieldNumbers(); err != nil {
+		c.report("%s: %v", name, err)
+	}
+	for _, r := range m.Extensions {
+		if r.Start < 1 || r.End > MaxFieldNumber || r.Start > r.End {
+			c.report("%s: invalid extension range %d to %d", name, r.Start, r.End)
+		}
+	}
+	c.checkReserved(name, m)
+	c.checkRanges(name, m)
+
+	for _, e := range m.Enums {
+		c.checkEnum(name, e)
+	}
+	for _, e := range m.Extends {
+		c.checkExtend(name, e)
+	}
+}
+
+// checkReserved reports invalid reserved ranges and fields using reserved numbers or names
+func (c *invariantChecker) checkReserved(name string, m *Message) {
+	for _, r := range m.Reserved {
+		if r.Start < 1 || r.End > MaxFieldNumber || r.Start > r.End {
+			c.report("%s: invalid reserved range %d to %d", name, r.Start, r.End)
+		}
+	}
+
+	reserved := newStringSet()
+	for _, n := range m.ReservedNames {
+		reserved.add(n)
+	}
+	check := func(field string, index int) {
+		if reserved.contains(field) {
+			c.report("%s: field name %s is reserved", qualify(name, field), field)
+		}
+		if m.IsReserved(index) {
+			c.report("%s: field number %d is reserved", qualify(name, field), index)
+		}
+	}
+
+	for _, f := range m.Fields {
+		check(f.Name, f.Index)
+	}
+	for _, f := range m.Maps {
+		check(f.Name, f.Index)
+	}
+	for _, o := range m.OneOfs {
+		for _, f := range o.Fields {
+			check(f.Name, f.Index)
+		}
+	}
+	for _, g := range m.Groups {
+		check(g.Name, g.Index)
+	}
+}
+
+// checkRanges reports fields in extension ranges and ranges overlapping each other
+func (c *invariantChecker) checkRanges(name string, m *Message) {
+	type numberRange struct {
+		kind       string
+		start, end int
+	}
+	var ranges []numberRange
+	for _, r := range m.Extensions {
+		ranges = append(ranges, numberRange{"extension", r.Start, r.End})
+	}
+	for _, r := range m.Reserved {
+		ranges = append(ranges, numberRange{"reserved", r.Start, r.End})
+	}
+	for i, r := range ranges {
+		for _, other := range ranges[:i] {
+			if r.start <= other.end && other.start <= r.end {
+				c.report("%s: %s range %d to %d overlaps %s range %d to %d", name, r.kind, r.start, r.end, other.kind, other.start, other.end)
+			}
+		}
+	}
+
+	check := func(field string, index int) {
+		for _, r := range m.Extensions {
+			if r.Start <= index && index <= r.End {
+				c.report("%s: field number %d is in extension range %d to %d", qualify(name, field), index, r.Start, r.End)
+			}
+		}
+	}
+	for _, f := range m.Fields {
+		check(f.Name, f.Index)
+	}
+	for _, f := range m.Maps {
+		check(f.Name, f.Index)
+	}
+	for _, o := range m.OneOfs {
+		for _, f := range o.Fields {
+			check(f.Name, f.Index)
+		}
+	}
+	for _, g := range m.Groups {
+		check(g.Name, g.Index)
+	}
+}
+
+func (c *invariantChecker) checkFieldNumber(scope, name string, index int) {
+	switch {
+	case index < 1 || index > MaxFieldNumber:
+		c.report("%s: field number %d is out of range", qualify(scope, name), index)
+	case firstReservedFieldNumber <= index && index <= lastReservedFieldNumber:
+		c.report("%s: field number %d is reserved for the protobuf implementation", qualify(scope, name), index)
+	}
+}
+
+func (c *invariantChecker) checkEnum(scope string, e *Enum) {
+	name := qualify(scope, e.Name)
+	if len(e.Fields) == 0 {
+		c.report("%s has no values", name)
+		return
+	}
+	if e.Fields[0].Index != 0 {
+		c.report("%s: the first value must be zero", name)
+	}
+
+	for _, r := range e.Reserved {
+		if r.Start > r.End {
+			c.report("%s: invalid reserved range %d to %d", name, r.Start, r.End)
+		}
+	}
+	reserved := newStringSet()
+	for _, n := range e.ReservedNames {
+		reserved.add(n)
+	}
+
+	numbers := map[int]string{}
+	for _, f := range e.Fields {
+		if other, ok := numbers[f.Index]; ok {
+			c.report("%s: value %s has the same number as %s", name, f.Name, other)
+		}
+		numbers[f.Index] = f.Name
+		if reserved.contains(f.Name) {
+			c.report("%s: value name %s is reserved", qualify(name, f.Name), f.Name)
+		}
+		if e.IsReserved(f.Index) {
+			c.report("%s: value number %d is reserved", qualify(name, f.Name), f.Index)
+		}
+	}
+}
+
+// enumValueNames returns the names of the values of the enums.
+// Enum values are scoped by the scope enclosing the enum, as in C++,
+// so that they must be unique there.
+func enumValueNames(es []*Enum) []string {
+	var names []string
+	for _, e := range es {
+		for _, f := range e.Fields {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func (c *invariantChecker) checkExtend(scope string, e *Extend) {
+	c.checkReference(scope, e.Type)
+	for _, f := range e.Fields {
+		c.checkReference(scope, f.Type)
+	}
+}
+
+// checkReference reports the reference when it resolves to nothing
+func (c *invariantChecker) checkReference(scope, ref string) {
+	if builtinTypes.contains(ref) {
+		return
+	}
+
+	pkg := string(c.file.Package)
+	name := ref
+	if strings.HasPrefix(name, ".") {
+		name = name[1:]
+		if pkg != "" && strings.HasPrefix(name, pkg+".") && c.types.contains(name[len(pkg)+1:]) {
+			return
+		}
+		if c.types.contains(name) {
+			return
+		}
+	} else {
+		for s := scope; ; s = ParentName(s) {
+			if c.types.contains(qualify(s, name)) {
+				return
+			}
+			if s == "" {
+				break
+			}
+		}
+		if pkg != "" && strings.HasPrefix(name, pkg+".") && c.types.contains(name[len(pkg)+1:]) {
+			return
+		}
+	}
+
+	if len(c.file.Imports) > 0 && c.imported(scope, name) {
+		return
+	}
+	if scope == "" {
+		c.report("unresolved type %s", ref)
+		return
+	}
+	c.report("%s: unresolved type %s", scope, ref)
+}
+
+// imported reports whether the name may refer to a type in an imported file,
+// i.e. it is not qualified or its leading component is not declared in the file.
+// A name qualified by the package of the file is looked up without the package
+// from the top level, where the types of the package declared in imports are.
+func (c *invariantChecker) imported(scope, name string) bool {
+	if pkg := string(c.file.Package); pkg != "" && strings.HasPrefix(name, pkg+".") {
+		name = name[len(pkg)+1:]
+		scope = ""
+	}
+	i := strings.Index(name, ".")
+	if i < 0 {
+		return true
+	}
+	for s := scope; ; s = ParentName(s) {
+		if c.types.contains(qualify(s, name[:i])) {
+			return false
+		}
+		if s == "" {
+			return true
+		}
+	}
+}
+
+var builtinTypes = newStringSetWith([]string{
+	string(Double), string(Float), string(Int32), string(Int64),
+	string(UInt32), string(UInt64), string(SInt32), string(SInt64),
+	string(Fixed32), string(Fixed64), string(SFixed32), string(SFixed64),
+	string(Bool), string(String), string(Bytes),
+})
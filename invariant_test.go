@@ -0,0 +1,154 @@
+package pbast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	table := []struct {
+		in       *File
+		expected []string
+	}{
+		{
+			in: NewFile("org.foo").
+				AddImport(NewImport("google/protobuf/timestamp.proto")).
+				AddMessage(NewMessage("M1").
+					AddField(NewMessageField(String, "f1", 1)).
+					AddField(NewMessageField(NewMessage("M1.Inner"), "f2", 2)).
+					AddField(NewMessageField(NewEnum("E1"), "f3", 3)).
+					AddField(NewMessageField(Timestamp, "f4", 4)).
+					AddMessage(NewMessage("Inner").
+						AddField(NewMessageField(NewMessage(".org.foo.M1"), "parent", 1)))).
+				AddEnum(NewEnum("E1").
+					AddField(NewEnumField("E1_UNSPECIFIED", 0))).
+				AddService(NewService("S1").
+					AddRPC(NewRPC("Get", NewReturnType("M1"), NewReturnType("M1.Inner")))),
+			expected: nil,
+		},
+		{
+			in: NewFile("org.foo").
+				AddMessage(NewMessage("M1").
+					AddField(NewMessageField(String, "f1", 0)).
+					AddField(NewMessageField(String, "f1", 19000)).
+					AddField(NewMessageField(NewMessage("Missing"), "f3", 19000)).
+					AddMapField(NewMapField(Double, String, "f4", 4))).
+				AddMessage(NewMessage("M1")).
+				AddEnum(NewEnum("E1").
+					AddField(NewEnumField("A", 1)).
+					AddField(NewEnumField("B", 1))).
+				AddService(NewService("S1").
+					AddRPC(NewRPC("Get", NewReturnType("M2"), NewReturnType("M1")))),
+			expected: []string{
+				"M1 is declared more than once",
+				"M1.f1: field number 0 is out of range",
+				"M1.f1: field number 19000 is reserved for the protobuf implementation",
+				"M1.f3: field number 19000 is reserved for the protobuf implementation",
+				"M1: unresolved type Missing",
+				"M1: double is not allowed as a key type of map f4",
+				"M1.f1 is declared more than once",
+				"M1: field number 19000 of f3 collides with f1 in message M1",
+				"E1: the first value must be zero",
+				"E1: value B has the same number as A",
+				"unresolved type M2",
+			},
+		},
+		{
+			in: NewFile("org.foo").
+				AddMessage(NewMessage("M1").
+					AddReservedRange(NewReservedRange(2, 3)).
+					AddReservedRange(NewReservedRange(5, 4)).
+					AddReservedName("f1").
+					AddField(NewMessageField(String, "f1", 1)).
+					AddField(NewMessageField(String, "f3", 3))),
+			expected: []string{
+				"M1: invalid reserved range 5 to 4",
+				"M1.f1: field name f1 is reserved",
+				"M1.f3: field number 3 is reserved",
+			},
+		},
+		{
+			in: NewFile("org.foo").
+				AddImport(NewImport("other/bar.proto")).
+				AddMessage(NewMessage("M1").
+					AddField(NewMessageField(NewMessage("other.Bar"), "f1", 1)).
+					AddField(NewMessageField(NewMessage("M1.Missing"), "f2", 2)).
+					AddField(NewMessageField(NewMessage("org.foo.Common"), "f3", 3)).
+					AddField(NewMessageField(NewMessage(".org.foo.M1.Missing"), "f4", 4)).
+					AddField(NewMessageField(NewMessage("Common"), "f5", 5))),
+			expected: []string{
+				"M1: unresolved type M1.Missing",
+				"M1: unresolved type .org.foo.M1.Missing",
+			},
+		},
+		{
+			in: NewFile("org.foo").
+				AddMessage(NewMessage("M1").
+					AddField(NewMessageField(NewMessage("Common"), "f1", 1))),
+			expected: []string{
+				"M1: unresolved type Common",
+			},
+		},
+		{
+			in: NewFile("org.foo").
+				AddEnum(NewEnum("E1").
+					AddField(NewEnumField("UNKNOWN", 0))).
+				AddEnum(NewEnum("E2").
+					AddField(NewEnumField("UNKNOWN", 0))).
+				AddMessage(NewMessage("M1").
+					AddExtensionRange(NewExtensionRange(100, 200)).
+					AddExtensionRange(NewExtensionRange(150, 300)).
+					AddReservedRange(NewReservedRange(10, 100)).
+					AddField(NewMessageField(String, "f1", 120)).
+					AddEnum(NewEnum("E3").
+						AddField(NewEnumField("f1", 0)))),
+			expected: []string{
+				"UNKNOWN is declared more than once",
+				"M1.f1 is declared more than once",
+				"M1: extension range 150 to 300 overlaps extension range 100 to 200",
+				"M1: reserved range 10 to 100 overlaps extension range 100 to 200",
+				"M1.f1: field number 120 is in extension range 100 to 200",
+			},
+		},
+		{
+			in: NewFile("org.foo").
+				AddEnum(NewEnum("E1").
+					AddReservedRange(NewReservedRange(2, 1)).
+					AddReservedRange(NewReservedRange(3, 4)).
+					AddReservedName("OLD").
+					AddField(NewEnumField("UNKNOWN", 0)).
+					AddField(NewEnumField("OLD", 1)).
+					AddField(NewEnumField("NEW", 4))),
+			expected: []string{
+				"E1: invalid reserved range 2 to 1",
+				"E1.OLD: value name OLD is reserved",
+				"E1.NEW: value number 4 is reserved",
+			},
+		},
+	}
+
+	for x, d := range table {
+		var actual []string
+		if err := CheckInvariants(d.in); err != nil {
+			actual = err.(*InvariantError).Problems
+		}
+		if !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("#%d: got %q, want %q", x, actual, d.expected)
+		}
+	}
+}
+
+func TestCheckInvariantsSelfNested(t *testing.T) {
+	m := NewMessage("M1")
+	m.AddMessage(NewMessage("Inner").AddMessage(m))
+	f := NewFile("org.foo").AddMessage(m)
+
+	err := CheckInvariants(f)
+	if err == nil {
+		t.Fatalf("got no error, want error")
+	}
+	expected := []string{"M1.Inner.M1: message nested in itself"}
+	if actual := err.(*InvariantError).Problems; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q, want %q", actual, expected)
+	}
+}
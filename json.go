@@ -0,0 +1,132 @@
+package pbast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// JSONVersion is the version of the JSON schema written by WriteJSON.
+// It is incremented when a field is renamed or removed.
+const JSONVersion = 1
+
+// jsonDocument is the top-level object of the JSON form of a file
+type jsonDocument struct {
+	Version int   `json:"version"`
+	File    *File `json:"file"`
+}
+
+// MarshalJSONFile returns the JSON form of the file
+func MarshalJSONFile(f *File) ([]byte, error) {
+	return json.MarshalIndent(jsonDocument{JSONVersion, f}, "", "  ")
+}
+
+// WriteJSON writes the JSON form of the file to w
+func WriteJSON(w io.Writer, f *File) error {
+	b, err := MarshalJSONFile(f)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// UnmarshalJSONFile reconstructs a file from its JSON form.
+// Unknown keys are rejected when built with Go 1.10 or later, and
+// the file is checked by CheckInvariants.
+func UnmarshalJSONFile(b []byte) (*File, error) {
+	return ReadJSON(bytes.NewReader(b))
+}
+
+// ReadJSON reads the JSON form of a file from r and reconstructs the file.
+// Unknown keys are rejected when built with Go 1.10 or later, and
+// the file is checked by CheckInvariants.
+func ReadJSON(r io.Reader) (*File, error) {
+	dec := json.NewDecoder(r)
+	disallowUnknownFields(dec)
+
+	var doc jsonDocument
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("unsupported JSON version %d, want %d", doc.Version, JSONVersion)
+	}
+	if doc.File == nil {
+		return nil, fmt.Errorf("no file in JSON")
+	}
+	if containsNull(reflect.ValueOf(doc.File).Elem()) {
+		return nil, fmt.Errorf("null element in JSON")
+	}
+	if err := CheckInvariants(doc.File); err != nil {
+		return nil, err
+	}
+	return doc.File, nil
+}
+
+// containsNull reports whether a slice in v holds a nil element,
+// which the Add methods never produce
+func containsNull(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return !v.IsNil() && containsNull(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if containsNull(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			if e.Kind() == reflect.Ptr && e.IsNil() {
+				return true
+			}
+			if containsNull(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s Syntax) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Syntax) UnmarshalText(b []byte) error {
+	if string(b) != s.String() {
+		return fmt.Errorf("unsupported syntax %q", b)
+	}
+	return nil
+}
+
+func (v Visibility) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *Visibility) UnmarshalText(b []byte) error {
+	for _, c := range []Visibility{NotSpecified, Weak, Public} {
+		if c.String() == string(b) {
+			*v = c
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown visibility %q", b)
+}
+
+func (l IdempotencyLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *IdempotencyLevel) UnmarshalText(b []byte) error {
+	for _, c := range []IdempotencyLevel{IdempotencyUnknown, NoSideEffects, Idempotent} {
+		if c.String() == string(b) {
+			*l = c
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown idempotency level %q", b)
+}
@@ -0,0 +1,12 @@
+//go:build go1.10
+// +build go1.10
+
+package pbast
+
+import "encoding/json"
+
+// disallowUnknownFields makes the decoder reject unknown keys,
+// which json.Decoder supports since Go 1.10
+func disallowUnknownFields(dec *json.Decoder) {
+	dec.DisallowUnknownFields()
+}
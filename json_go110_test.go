@@ -0,0 +1,16 @@
+//go:build go1.10
+// +build go1.10
+
+package pbast
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadJSONUnknownKey(t *testing.T) {
+	in := `{"version": 1, "file": {"syntax": "proto3", "unknown": 1}}`
+	if _, err := ReadJSON(strings.NewReader(in)); err == nil {
+		t.Errorf("got no error, want error")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !go1.10
+// +build !go1.10
+
+package pbast
+
+import "encoding/json"
+
+// disallowUnknownFields does nothing, unknown keys are ignored before Go 1.10
+func disallowUnknownFields(dec *json.Decoder) {}
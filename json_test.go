@@ -0,0 +1,107 @@
+package pbast
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	f := jsonTestFile()
+
+	expected := `{
+  "version": 1,
+  "file": {
+    "syntax": "proto3",
+    "package": "org.foo",
+    "imports": [
+      {
+        "name": "a.proto",
+        "visibility": "public"
+      }
+    ],
+    "messages": [
+      {
+        "name": "Human",
+        "fields": [
+          {
+            "type": "string",
+            "name": "name",
+            "index": 1
+          }
+        ]
+      }
+    ],
+    "services": [
+      {
+        "name": "HumanService",
+        "rpcs": [
+          {
+            "name": "GetHuman",
+            "input": {
+              "name": "Human"
+            },
+            "output": {
+              "name": "Human"
+            },
+            "idempotencyLevel": "NO_SIDE_EFFECTS"
+          }
+        ]
+      }
+    ]
+  }
+}
+`
+
+	buf := new(bytes.Buffer)
+	if err := WriteJSON(buf, f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	expected := jsonTestFile()
+	b, err := MarshalJSONFile(expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual, err := UnmarshalJSONFile(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %+v, want %+v", actual, expected)
+	}
+}
+
+func TestReadJSONError(t *testing.T) {
+	table := []string{
+		`{"version": 2, "file": {"syntax": "proto3"}}`,
+		`{"version": 1}`,
+		`{"version": 1, "file": {"syntax": "proto2"}}`,
+		`{"version": 1, "file": {"syntax": "proto3", "messages": [null]}}`,
+		`{"version": 1, "file": {"syntax": "proto3", "imports": [{"name": "a.proto", "visibility": "private"}]}}`,
+		`{"version": 1, "file": {"syntax": "proto3", "messages": [{"name": "A"}, {"name": "A"}]}}`,
+	}
+
+	for x, in := range table {
+		if _, err := ReadJSON(strings.NewReader(in)); err == nil {
+			t.Errorf("#%d: got no error, want error", x)
+		}
+	}
+}
+
+func jsonTestFile() *File {
+	return NewFile("org.foo").
+		AddImport(NewPublicImport("a.proto")).
+		AddMessage(NewMessage("Human").
+			AddField(NewMessageField(String, "name", 1))).
+		AddService(NewService("HumanService").
+			AddRPC(NewRPC("GetHuman", NewReturnType("Human"), NewReturnType("Human")).
+				SetIdempotencyLevel(NoSideEffects)))
+}
@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/oshothebig/pbast"
+)
+
+// bufRuleIDs maps the rule names onto buf's lint rule IDs
+var bufRuleIDs = map[string]string{
+	"message-pascal-case":         "MESSAGE_PASCAL_CASE",
+	"field-snake-case":            "FIELD_LOWER_SNAKE_CASE",
+	"oneof-snake-case":            "ONEOF_LOWER_SNAKE_CASE",
+	"enum-pascal-case":            "ENUM_PASCAL_CASE",
+	"enum-value-upper-snake-case": "ENUM_VALUE_UPPER_SNAKE_CASE",
+	"enum-value-prefix":           "ENUM_VALUE_PREFIX",
+	"enum-zero-value-suffix":      "ENUM_ZERO_VALUE_SUFFIX",
+	"service-pascal-case":         "SERVICE_PASCAL_CASE",
+	"rpc-pascal-case":             "RPC_PASCAL_CASE",
+}
+
+// BufRuleID returns buf's lint rule ID corresponding to the violation.
+// The rule name is returned as is when buf has no equivalent rule.
+func BufRuleID(v Violation) string {
+	if v.Rule == FileLayout.Name() {
+		switch v.Node.(type) {
+		case *pbast.File:
+			return "PACKAGE_DEFINED"
+		case pbast.Package:
+			return "PACKAGE_LOWER_SNAKE_CASE"
+		}
+	}
+
+	if id, ok := bufRuleIDs[v.Rule]; ok {
+		return id
+	}
+	return v.Rule
+}
+
+// BufAnnotation is a violation in the form of buf's JSON error format
+type BufAnnotation struct {
+	Path    string `json:"path"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// NewBufAnnotation converts a violation found in the file at the path
+func NewBufAnnotation(path string, v Violation) *BufAnnotation {
+	msg := v.Message
+	if v.Position != "" {
+		msg = v.Position + ": " + msg
+	}
+	return &BufAnnotation{
+		Path:    path,
+		Type:    BufRuleID(v),
+		Message: msg,
+	}
+}
+
+// WriteBufJSON writes the violations found in the file at the path
+// as buf does with --error-format=json, one JSON object per line
+func WriteBufJSON(w io.Writer, path string, vs []Violation) error {
+	enc := json.NewEncoder(w)
+	for _, v := range vs {
+		if err := enc.Encode(NewBufAnnotation(path, v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package lint
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestWriteBufJSON(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		expected string
+	}{
+		{
+			in:       pbast.NewFile("org.foo"),
+			expected: "",
+		},
+		{
+			in: pbast.NewFile("").
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.String, "firstName", 1))),
+			expected: `{"path":"human.proto","type":"PACKAGE_DEFINED","message":"file should declare a package"}
+{"path":"human.proto","type":"FIELD_LOWER_SNAKE_CASE","message":"Human.firstName: field name should be lower_snake_case"}
+`,
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddImport(pbast.NewImport("a.proto")).
+				AddImport(pbast.NewImport("a.proto")).
+				AddEnum(pbast.NewEnum("Sex").
+					AddField(pbast.NewEnumField("SEX_MALE", 0))),
+			expected: `{"path":"human.proto","type":"file-layout","message":"a.proto: file is imported more than once"}
+{"path":"human.proto","type":"ENUM_ZERO_VALUE_SUFFIX","message":"Sex.SEX_MALE: enum zero value name should be suffixed with _UNSPECIFIED"}
+`,
+		},
+	}
+
+	for x, d := range table {
+		buf := new(bytes.Buffer)
+		if err := WriteBufJSON(buf, "human.proto", Lint(d.in)); err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+		}
+		if buf.String() != d.expected {
+			t.Errorf("#%d:\ngot\n%s\nwant\n%s", x, buf.String(), d.expected)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	pascalCase     = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+	lowerSnakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	upperSnakeCase = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+	lowerDotted    = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)*$`)
+)
+
+func isPascalCase(s string) bool {
+	return pascalCase.MatchString(s)
+}
+
+func isLowerSnakeCase(s string) bool {
+	return lowerSnakeCase.MatchString(s)
+}
+
+func isUpperSnakeCase(s string) bool {
+	return upperSnakeCase.MatchString(s)
+}
+
+// words splits an identifier into words at underscores, hyphens, dots
+// and case boundaries, e.g. "HTTPServer_name" into "HTTP", "Server" and "name"
+func words(s string) []string {
+	var ws []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			ws = append(ws, string(current))
+			current = nil
+		}
+	}
+
+	rs := []rune(s)
+	for i, r := range rs {
+		switch {
+		case r == '_' || r == '-' || r == '.' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return ws
+}
+
+func toPascalCase(s string) string {
+	ws := words(s)
+	for i, w := range ws {
+		ws[i] = strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	}
+	return strings.Join(ws, "")
+}
+
+func toLowerSnakeCase(s string) string {
+	return strings.ToLower(strings.Join(words(s), "_"))
+}
+
+func toUpperSnakeCase(s string) string {
+	return strings.ToUpper(strings.Join(words(s), "_"))
+}
@@ -0,0 +1,63 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/rewrite"
+)
+
+// Change represents a modification made by a fixer.
+// New is empty when the node is removed.
+type Change struct {
+	Rule string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	if c.New == "" {
+		return fmt.Sprintf("%s removed (%s)", c.Old, c.Rule)
+	}
+	return fmt.Sprintf("%s -> %s (%s)", c.Old, c.New, c.Rule)
+}
+
+// Fix corrects the violations of DefaultRules.
+// The file is modified in place and returned with the log of changes and
+// the violations left, e.g. a missing package or a fixed name taken by another
+// declaration, which have to be corrected by hand.
+func Fix(f *pbast.File) (*pbast.File, []Change, []Violation) {
+	return RunFix(f, DefaultRules...)
+}
+
+// RunFix applies the fixers of the rules in order and checks the file against
+// the rules again. Rules not implementing Fixer are only checked.
+func RunFix(f *pbast.File, rules ...Rule) (*pbast.File, []Change, []Violation) {
+	var cs []Change
+	for _, r := range rules {
+		if fixer, ok := r.(Fixer); ok {
+			cs = append(cs, fixer.Fix(f)...)
+		}
+	}
+	return f, cs, Run(f, rules...)
+}
+
+// renameFix returns a fix renaming the nodes by rewrite.Rename
+func renameFix(fn rewrite.RenameFunc) func(*pbast.File) []Change {
+	return func(f *pbast.File) []Change {
+		renamed := rewrite.Rename(f, fn)
+
+		olds := make([]string, 0, len(renamed))
+		for old := range renamed {
+			olds = append(olds, old)
+		}
+		sort.Strings(olds)
+
+		cs := make([]Change, 0, len(olds))
+		for _, old := range olds {
+			cs = append(cs, Change{Old: old, New: renamed[old]})
+		}
+		return cs
+	}
+}
@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/oshothebig/pbast"
+)
+
+// Violation represents a node which doesn't conform to a rule
+type Violation struct {
+	Rule string
+	// Position is the dotted path to the node from the file, e.g. "Outer.Inner.field"
+	Position string
+	Node     pbast.Node
+	Message  string
+}
+
+func (v Violation) String() string {
+	if v.Position == "" {
+		return fmt.Sprintf("%s (%s)", v.Message, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s (%s)", v.Position, v.Message, v.Rule)
+}
+
+// Rule checks a file and reports the violations found
+type Rule interface {
+	Name() string
+	Check(f *pbast.File) []Violation
+}
+
+// Lint checks the file against DefaultRules
+func Lint(f *pbast.File) []Violation {
+	return Run(f, DefaultRules...)
+}
+
+// Run checks the file against the rules in order
+func Run(f *pbast.File, rules ...Rule) []Violation {
+	var vs []Violation
+	for _, r := range rules {
+		vs = append(vs, r.Check(f)...)
+	}
+	return vs
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// walkMessages calls fn for every message in the file including nested ones
+// with the dotted path to the message
+func walkMessages(f *pbast.File, fn func(path string, m *pbast.Message)) {
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		fn(qualify(scope, m.Name), m)
+	})
+}
+
+// walkEnums calls fn for every enum in the file including nested ones
+// with the dotted path to the enum
+func walkEnums(f *pbast.File, fn func(path string, e *pbast.Enum)) {
+	for _, e := range f.Enums {
+		fn(e.Name, e)
+	}
+	walkMessages(f, func(path string, m *pbast.Message) {
+		for _, e := range m.Enums {
+			fn(qualify(path, e.Name), e)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestLint(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		expected []string
+	}{
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.String, "first_name", 1))).
+				AddEnum(pbast.NewEnum("Sex").
+					AddField(pbast.NewEnumField("SEX_UNSPECIFIED", 0)).
+					AddField(pbast.NewEnumField("SEX_MALE", 1))).
+				AddService(pbast.NewService("HumanService").
+					AddRPC(pbast.NewRPC("GetHuman", pbast.NewReturnType("Human"), pbast.NewReturnType("Human")))),
+			expected: nil,
+		},
+		{
+			in: pbast.NewFile("").
+				AddMessage(pbast.NewMessage("human").
+					AddField(pbast.NewMessageField(pbast.String, "firstName", 1)).
+					AddMessage(pbast.NewMessage("Inner_message"))),
+			expected: []string{
+				"file should declare a package (file-layout)",
+				"human: message name should be PascalCase (message-pascal-case)",
+				"human.Inner_message: message name should be PascalCase (message-pascal-case)",
+				"human.firstName: field name should be lower_snake_case (field-snake-case)",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Search").
+					AddGroup(pbast.NewRepeatedGroup("result_item", 1).
+						AddField(pbast.NewMessageField(pbast.String, "pageUrl", 2)))),
+			expected: []string{
+				"Search.result_item: group name should be PascalCase (message-pascal-case)",
+				"Search.result_item.pageUrl: field name should be lower_snake_case (field-snake-case)",
+			},
+		},
+		{
+			in: pbast.NewFile("org.Foo").
+				AddImport(pbast.NewImport("a.proto")).
+				AddImport(pbast.NewImport("a.proto")),
+			expected: []string{
+				"package name should be lower-case and dot-separated (file-layout)",
+				"a.proto: file is imported more than once (file-layout)",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddEnum(pbast.NewEnum("HTTPStatus").
+					AddField(pbast.NewEnumField("HTTP_STATUS_UNKNOWN", 0)).
+					AddField(pbast.NewEnumField("ok", 1))),
+			expected: []string{
+				"HTTPStatus.ok: enum value name should be UPPER_SNAKE_CASE (enum-value-upper-snake-case)",
+				"HTTPStatus.ok: enum value name should be prefixed with HTTP_STATUS_ (enum-value-prefix)",
+				"HTTPStatus.HTTP_STATUS_UNKNOWN: enum zero value name should be suffixed with _UNSPECIFIED (enum-zero-value-suffix)",
+			},
+		},
+	}
+
+	for x, d := range table {
+		var actual []string
+		for _, v := range Lint(d.in) {
+			actual = append(actual, v.String())
+		}
+		if !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("#%d: got %q, want %q", x, actual, d.expected)
+		}
+	}
+}
+
+func TestCaseConversion(t *testing.T) {
+	table := []struct {
+		in     string
+		pascal string
+		lower  string
+		upper  string
+	}{
+		{"firstName", "FirstName", "first_name", "FIRST_NAME"},
+		{"HTTPServer", "HttpServer", "http_server", "HTTP_SERVER"},
+		{"ipv4-address", "Ipv4Address", "ipv4_address", "IPV4_ADDRESS"},
+		{"SEX_MALE", "SexMale", "sex_male", "SEX_MALE"},
+	}
+
+	for x, d := range table {
+		if actual := toPascalCase(d.in); actual != d.pascal {
+			t.Errorf("#%d: got %s, want %s", x, actual, d.pascal)
+		}
+		if actual := toLowerSnakeCase(d.in); actual != d.lower {
+			t.Errorf("#%d: got %s, want %s", x, actual, d.lower)
+		}
+		if actual := toUpperSnakeCase(d.in); actual != d.upper {
+			t.Errorf("#%d: got %s, want %s", x, actual, d.upper)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	f := pbast.NewFile("org.Foo-Bar").
+		AddImport(pbast.NewImport("a.proto")).
+		AddImport(pbast.NewImport("a.proto")).
+		AddMessage(pbast.NewMessage("human_being").
+			AddField(pbast.NewMessageField(pbast.String, "firstName", 1)).
+			AddField(pbast.NewMessageField(pbast.NewEnum("sex"), "sex", 2)).
+			AddField(pbast.NewMessageField(pbast.NewMessage(".org.Foo-Bar.human_being"), "parent", 3))).
+		AddEnum(pbast.NewEnum("sex").
+			AddField(pbast.NewEnumField("SEX_UNKNOWN", 0)).
+			AddField(pbast.NewEnumField("male", 1))).
+		AddService(pbast.NewService("humans").
+			AddRPC(pbast.NewRPC("get", pbast.NewReturnType("human_being"), pbast.NewReturnType("human_being"))))
+
+	expected := pbast.NewFile("org.foo_bar").
+		AddImport(pbast.NewImport("a.proto")).
+		AddMessage(pbast.NewMessage("HumanBeing").
+			AddField(pbast.NewMessageField(pbast.String, "first_name", 1)).
+			AddField(pbast.NewMessageField(pbast.NewEnum("Sex"), "sex", 2)).
+			AddField(pbast.NewMessageField(pbast.NewMessage(".org.foo_bar.HumanBeing"), "parent", 3))).
+		AddEnum(pbast.NewEnum("Sex").
+			AddField(pbast.NewEnumField("SEX_UNKNOWN_UNSPECIFIED", 0)).
+			AddField(pbast.NewEnumField("SEX_MALE", 1))).
+		AddService(pbast.NewService("Humans").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("HumanBeing"), pbast.NewReturnType("HumanBeing"))))
+	expected.Renames = pbast.RenameTable{
+		"human_being":           "HumanBeing",
+		"human_being.firstName": "HumanBeing.first_name",
+		"sex":                   "Sex",
+		"sex.male":              "Sex.SEX_MALE",
+		"sex.SEX_UNKNOWN":       "Sex.SEX_UNKNOWN_UNSPECIFIED",
+		"humans":                "Humans",
+		"humans.get":            "Humans.Get",
+	}
+
+	expectedChanges := []string{
+		"org.Foo-Bar -> org.foo_bar (file-layout)",
+		"a.proto removed (file-layout)",
+		"human_being -> HumanBeing (message-pascal-case)",
+		"HumanBeing.firstName -> HumanBeing.first_name (field-snake-case)",
+		"sex -> Sex (enum-pascal-case)",
+		"Sex.male -> Sex.MALE (enum-value-upper-snake-case)",
+		"Sex.MALE -> Sex.SEX_MALE (enum-value-prefix)",
+		"Sex.SEX_UNKNOWN -> Sex.SEX_UNKNOWN_UNSPECIFIED (enum-zero-value-suffix)",
+		"humans -> Humans (service-pascal-case)",
+		"Humans.get -> Humans.Get (rpc-pascal-case)",
+	}
+
+	actual, cs, vs := Fix(f)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %+v, want %+v", actual, expected)
+	}
+
+	var changes []string
+	for _, c := range cs {
+		changes = append(changes, c.String())
+	}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("got %q, want %q", changes, expectedChanges)
+	}
+
+	if len(vs) != 0 {
+		t.Errorf("got %v, want no violations", vs)
+	}
+	if vs := Lint(actual); len(vs) != 0 {
+		t.Errorf("got %v, want no violations", vs)
+	}
+	if err := pbast.CheckInvariants(actual); err != nil {
+		t.Errorf("got %v, want no error", err)
+	}
+
+	// violations which can't be fixed are returned
+	_, _, vs = Fix(pbast.NewFile("").
+		AddEnum(pbast.NewEnum("Sex").
+			AddField(pbast.NewEnumField("SEX_UNKNOWN", 0)).
+			AddField(pbast.NewEnumField("SEX_UNKNOWN_UNSPECIFIED", 1))))
+	var remaining []string
+	for _, v := range vs {
+		remaining = append(remaining, v.String())
+	}
+	expectedRemaining := []string{
+		"file should declare a package (file-layout)",
+		"Sex.SEX_UNKNOWN_UNSPECIFIED_: enum value name should be UPPER_SNAKE_CASE (enum-value-upper-snake-case)",
+		"Sex.SEX_UNKNOWN_UNSPECIFIED_: enum zero value name should be suffixed with _UNSPECIFIED (enum-zero-value-suffix)",
+	}
+	if !reflect.DeepEqual(remaining, expectedRemaining) {
+		t.Errorf("got %q, want %q", remaining, expectedRemaining)
+	}
+}
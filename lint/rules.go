@@ -0,0 +1,427 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/rewrite"
+)
+
+// DefaultRules are the rules derived from Google's protobuf style guide
+var DefaultRules = []Rule{
+	FileLayout,
+	MessagePascalCase,
+	FieldSnakeCase,
+	OneOfSnakeCase,
+	EnumPascalCase,
+	EnumValueUpperSnakeCase,
+	EnumValuePrefix,
+	EnumZeroValueSuffix,
+	ServicePascalCase,
+	RPCPascalCase,
+}
+
+type rule struct {
+	name  string
+	check func(f *pbast.File) []Violation
+}
+
+func (r *rule) Name() string {
+	return r.name
+}
+
+func (r *rule) Check(f *pbast.File) []Violation {
+	vs := r.check(f)
+	for i := range vs {
+		vs[i].Rule = r.name
+	}
+	return vs
+}
+
+// Fixer is implemented by rules which can correct their violations
+type Fixer interface {
+	Rule
+	Fix(f *pbast.File) []Change
+}
+
+type fixableRule struct {
+	rule
+	fix func(f *pbast.File) []Change
+}
+
+func (r *fixableRule) Fix(f *pbast.File) []Change {
+	cs := r.fix(f)
+	for i := range cs {
+		cs[i].Rule = r.name
+	}
+	return cs
+}
+
+// FileLayout requires a file to declare a lower-case dotted package
+// and to import each file only once
+var FileLayout Rule = &fixableRule{
+	rule: rule{
+		name: "file-layout",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			switch {
+			case f.Package == "":
+				vs = append(vs, Violation{
+					Node:    f,
+					Message: "file should declare a package",
+				})
+			case !lowerDotted.MatchString(string(f.Package)):
+				vs = append(vs, Violation{
+					Node:    f.Package,
+					Message: "package name should be lower-case and dot-separated",
+				})
+			}
+
+			imported := map[string]bool{}
+			for _, i := range f.Imports {
+				if imported[i.Name] {
+					vs = append(vs, Violation{
+						Position: i.Name,
+						Node:     i,
+						Message:  "file is imported more than once",
+					})
+				}
+				imported[i.Name] = true
+			}
+			return vs
+		},
+	},
+	fix: func(f *pbast.File) []Change {
+		var cs []Change
+		if pkg := sanitizePackage(f.Package); pkg != f.Package {
+			cs = append(cs, Change{Old: string(f.Package), New: string(pkg)})
+			rewrite.RenamePackage(f, pkg)
+		}
+		for _, i := range rewrite.RemoveDuplicateImports(f) {
+			cs = append(cs, Change{Old: i.Name})
+		}
+		return cs
+	},
+}
+
+// MessagePascalCase requires message names, including groups declaring messages, to be PascalCase
+var MessagePascalCase Rule = &fixableRule{
+	rule: rule{
+		name: "message-pascal-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkMessages(f, func(path string, m *pbast.Message) {
+				if !isPascalCase(m.Name) {
+					vs = append(vs, Violation{
+						Position: path,
+						Node:     m,
+						Message:  "message name should be PascalCase",
+					})
+				}
+				for _, g := range m.Groups {
+					if !isPascalCase(g.Name) {
+						vs = append(vs, Violation{
+							Position: qualify(path, g.Name),
+							Node:     g,
+							Message:  "group name should be PascalCase",
+						})
+					}
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		switch n.(type) {
+		case *pbast.Message, *pbast.Group:
+			if !isPascalCase(pbast.BaseName(name)) {
+				return toPascalCase(pbast.BaseName(name))
+			}
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// FieldSnakeCase requires field names, including maps, oneof and group members, to be lower_snake_case
+var FieldSnakeCase Rule = &fixableRule{
+	rule: rule{
+		name: "field-snake-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkMessages(f, func(path string, m *pbast.Message) {
+				for _, field := range m.Fields {
+					if !isLowerSnakeCase(field.Name) {
+						vs = append(vs, Violation{
+							Position: qualify(path, field.Name),
+							Node:     field,
+							Message:  "field name should be lower_snake_case",
+						})
+					}
+				}
+				for _, field := range m.Maps {
+					if !isLowerSnakeCase(field.Name) {
+						vs = append(vs, Violation{
+							Position: qualify(path, field.Name),
+							Node:     field,
+							Message:  "field name should be lower_snake_case",
+						})
+					}
+				}
+				for _, o := range m.OneOfs {
+					for _, field := range o.Fields {
+						if !isLowerSnakeCase(field.Name) {
+							vs = append(vs, Violation{
+								Position: qualify(path, field.Name),
+								Node:     field,
+								Message:  "field name should be lower_snake_case",
+							})
+						}
+					}
+				}
+				for _, g := range m.Groups {
+					for _, field := range g.Fields {
+						if !isLowerSnakeCase(field.Name) {
+							vs = append(vs, Violation{
+								Position: qualify(qualify(path, g.Name), field.Name),
+								Node:     field,
+								Message:  "field name should be lower_snake_case",
+							})
+						}
+					}
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		switch n.(type) {
+		case *pbast.MessageField, *pbast.MapField, *pbast.OneOfField:
+			if !isLowerSnakeCase(pbast.BaseName(name)) {
+				return toLowerSnakeCase(pbast.BaseName(name))
+			}
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// OneOfSnakeCase requires oneof names to be lower_snake_case
+var OneOfSnakeCase Rule = &fixableRule{
+	rule: rule{
+		name: "oneof-snake-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkMessages(f, func(path string, m *pbast.Message) {
+				for _, o := range m.OneOfs {
+					if !isLowerSnakeCase(o.Name) {
+						vs = append(vs, Violation{
+							Position: qualify(path, o.Name),
+							Node:     o,
+							Message:  "oneof name should be lower_snake_case",
+						})
+					}
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.OneOf); ok && !isLowerSnakeCase(pbast.BaseName(name)) {
+			return toLowerSnakeCase(pbast.BaseName(name))
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// EnumPascalCase requires enum names to be PascalCase
+var EnumPascalCase Rule = &fixableRule{
+	rule: rule{
+		name: "enum-pascal-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkEnums(f, func(path string, e *pbast.Enum) {
+				if !isPascalCase(e.Name) {
+					vs = append(vs, Violation{
+						Position: path,
+						Node:     e,
+						Message:  "enum name should be PascalCase",
+					})
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.Enum); ok && !isPascalCase(pbast.BaseName(name)) {
+			return toPascalCase(pbast.BaseName(name))
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// EnumValueUpperSnakeCase requires enum value names to be UPPER_SNAKE_CASE
+var EnumValueUpperSnakeCase Rule = &fixableRule{
+	rule: rule{
+		name: "enum-value-upper-snake-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkEnums(f, func(path string, e *pbast.Enum) {
+				for _, field := range e.Fields {
+					if !isUpperSnakeCase(field.Name) {
+						vs = append(vs, Violation{
+							Position: qualify(path, field.Name),
+							Node:     field,
+							Message:  "enum value name should be UPPER_SNAKE_CASE",
+						})
+					}
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.EnumField); ok && !isUpperSnakeCase(pbast.BaseName(name)) {
+			return toUpperSnakeCase(pbast.BaseName(name))
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// EnumValuePrefix requires enum value names to be prefixed with
+// the enum name in UPPER_SNAKE_CASE, e.g. FOO_BAR_ for enum FooBar
+var EnumValuePrefix Rule = &fixableRule{
+	rule: rule{
+		name: "enum-value-prefix",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkEnums(f, func(path string, e *pbast.Enum) {
+				prefix := enumValuePrefix(e)
+				for _, field := range e.Fields {
+					if !strings.HasPrefix(field.Name, prefix) {
+						vs = append(vs, Violation{
+							Position: qualify(path, field.Name),
+							Node:     field,
+							Message:  "enum value name should be prefixed with " + prefix,
+						})
+					}
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.EnumField); ok {
+			prefix := toUpperSnakeCase(pbast.BaseName(pbast.ParentName(name))) + "_"
+			if !strings.HasPrefix(pbast.BaseName(name), prefix) {
+				return prefix + pbast.BaseName(name)
+			}
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// EnumZeroValueSuffix requires the zero value of an enum
+// to be suffixed with _UNSPECIFIED
+var EnumZeroValueSuffix Rule = &fixableRule{
+	rule: rule{
+		name: "enum-zero-value-suffix",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			walkEnums(f, func(path string, e *pbast.Enum) {
+				for _, field := range e.Fields {
+					if field.Index == 0 && !strings.HasSuffix(field.Name, zeroValueSuffix) {
+						vs = append(vs, Violation{
+							Position: qualify(path, field.Name),
+							Node:     field,
+							Message:  "enum zero value name should be suffixed with " + zeroValueSuffix,
+						})
+					}
+				}
+			})
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if v, ok := n.(*pbast.EnumField); ok && v.Index == 0 && !strings.HasSuffix(pbast.BaseName(name), zeroValueSuffix) {
+			return pbast.BaseName(name) + zeroValueSuffix
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// ServicePascalCase requires service names to be PascalCase
+var ServicePascalCase Rule = &fixableRule{
+	rule: rule{
+		name: "service-pascal-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			for _, s := range f.Services {
+				if !isPascalCase(s.Name) {
+					vs = append(vs, Violation{
+						Position: s.Name,
+						Node:     s,
+						Message:  "service name should be PascalCase",
+					})
+				}
+			}
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.Service); ok && !isPascalCase(pbast.BaseName(name)) {
+			return toPascalCase(pbast.BaseName(name))
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+// RPCPascalCase requires RPC names to be PascalCase
+var RPCPascalCase Rule = &fixableRule{
+	rule: rule{
+		name: "rpc-pascal-case",
+		check: func(f *pbast.File) []Violation {
+			var vs []Violation
+			for _, s := range f.Services {
+				for _, r := range s.RPCs {
+					if !isPascalCase(r.Name) {
+						vs = append(vs, Violation{
+							Position: qualify(s.Name, r.Name),
+							Node:     r,
+							Message:  "RPC name should be PascalCase",
+						})
+					}
+				}
+			}
+			return vs
+		},
+	},
+	fix: renameFix(func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.RPC); ok && !isPascalCase(pbast.BaseName(name)) {
+			return toPascalCase(pbast.BaseName(name))
+		}
+		return pbast.BaseName(name)
+	}),
+}
+
+const zeroValueSuffix = "_UNSPECIFIED"
+
+// sanitizePackage returns the package with each component in lower_snake_case
+// and the characters not allowed in a package name removed
+func sanitizePackage(pkg pbast.Package) pbast.Package {
+	var components []string
+	for _, c := range strings.Split(string(pkg), ".") {
+		c = strings.Map(func(r rune) rune {
+			if 'a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '_' {
+				return r
+			}
+			return -1
+		}, toLowerSnakeCase(c))
+		if c != "" {
+			components = append(components, c)
+		}
+	}
+	return pbast.Package(strings.Join(components, "."))
+}
+
+func enumValuePrefix(e *pbast.Enum) string {
+	return toUpperSnakeCase(e.Name) + "_"
+}
@@ -0,0 +1,131 @@
+package pbast
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConflictError reports top-level declarations defined differently among files
+type ConflictError struct {
+	Names []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting definitions: %s", strings.Join(e.Names, ", "))
+}
+
+// Merge combines files declaring the same package into a new file.
+// Imports are deduplicated and identical declarations appearing in more
+// than one file are merged into one. When top-level messages, enums, services
+// or options of the same name, or extension fields of the same name or number,
+// are defined differently, a *ConflictError listing them is returned. Declarations in the result are shared with the inputs.
+// The rename tables of the files are combined; a name renamed in more than
+// one file keeps the rename recorded in the earliest file.
+func Merge(files ...*File) (*File, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no files to merge")
+	}
+
+	merged := NewFile(files[0].Package)
+	for _, f := range files[1:] {
+		if f.Package != merged.Package {
+			return nil, fmt.Errorf("package %s differs from %s", f.Package, merged.Package)
+		}
+	}
+
+	imports := newStringSet()
+	options := map[string]*Option{}
+	decls := map[string]interface{}{}
+	conflicts := newStringSet()
+	var names []string
+
+	// declare returns whether the declaration needs to be added
+	declare := func(name string, decl interface{}) bool {
+		prev, ok := decls[name]
+		if !ok {
+			decls[name] = decl
+			return true
+		}
+		if !reflect.DeepEqual(prev, decl) && !conflicts.contains(name) {
+			conflicts.add(name)
+			names = append(names, name)
+		}
+		return false
+	}
+
+	for _, f := range files {
+		if len(merged.Comment) == 0 {
+			merged.Comment = f.Comment
+		}
+		for _, i := range f.Imports {
+			if imports.contains(i.Name) {
+				continue
+			}
+			imports.add(i.Name)
+			merged.AddImport(i)
+		}
+		for _, o := range f.Options {
+			prev, ok := options[o.Name]
+			if !ok {
+				options[o.Name] = o
+				merged.AddOption(o)
+				continue
+			}
+			if prev.Value != o.Value && !conflicts.contains("option "+o.Name) {
+				conflicts.add("option " + o.Name)
+				names = append(names, "option "+o.Name)
+			}
+		}
+		for _, m := range f.Messages {
+			if declare(m.Name, m) {
+				merged.AddMessage(m)
+			}
+		}
+		for _, e := range f.Enums {
+			if declare(e.Name, e) {
+				merged.AddEnum(e)
+			}
+		}
+		for _, e := range f.Extends {
+			target := strings.TrimPrefix(e.Type, ".")
+			var fields []*MessageField
+			for _, field := range e.Fields {
+				byName := declare(fmt.Sprintf("extend %s.%s", target, field.Name), field)
+				byNumber := declare(fmt.Sprintf("extend %s = %d", target, field.Index), field)
+				if byName && byNumber {
+					fields = append(fields, field)
+				}
+			}
+			switch {
+			case len(e.Fields) == 0:
+				if declare("extend "+target, e) {
+					merged.AddExtend(e)
+				}
+			case len(fields) == len(e.Fields):
+				merged.AddExtend(e)
+			case len(fields) > 0:
+				merged.AddExtend(&Extend{Type: e.Type, Comment: e.Comment, Fields: fields})
+			}
+		}
+		for _, s := range f.Services {
+			if declare(s.Name, s) {
+				merged.AddService(s)
+			}
+		}
+		for old, name := range f.Renames {
+			if merged.Renames == nil {
+				merged.Renames = RenameTable{}
+			}
+			if _, ok := merged.Renames[old]; !ok {
+				merged.Renames[old] = name
+			}
+		}
+	}
+
+	if len(names) > 0 {
+		return nil, &ConflictError{Names: names}
+	}
+	return merged, nil
+}
@@ -0,0 +1,113 @@
+package pbast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	f1 := NewFile("org.foo").
+		AddImport(NewImport("a.proto")).
+		AddOption(NewOption("java_package", `"org.foo"`)).
+		AddMessage(NewMessage("M1")).
+		AddMessage(NewMessage("Shared").
+			AddField(NewMessageField(String, "name", 1))).
+		AddExtend(NewExtend(FieldOptions).
+			AddField(NewMessageField(String, "tag", 50000)))
+	f2 := NewFile("org.foo").
+		AddImport(NewImport("a.proto")).
+		AddImport(NewImport("b.proto")).
+		AddOption(NewOption("java_package", `"org.foo"`)).
+		AddMessage(NewMessage("Shared").
+			AddField(NewMessageField(String, "name", 1))).
+		AddEnum(NewEnum("E1")).
+		AddExtend(NewExtend(FieldOptions).
+			AddField(NewMessageField(String, "tag", 50000))).
+		AddExtend(NewExtend(FieldOptions).
+			AddField(NewMessageField(String, "tag", 50000)).
+			AddField(NewMessageField(Int32, "weight", 50001))).
+		AddService(NewService("S1"))
+
+	expected := NewFile("org.foo").
+		AddImport(NewImport("a.proto")).
+		AddImport(NewImport("b.proto")).
+		AddOption(NewOption("java_package", `"org.foo"`)).
+		AddMessage(NewMessage("M1")).
+		AddMessage(NewMessage("Shared").
+			AddField(NewMessageField(String, "name", 1))).
+		AddEnum(NewEnum("E1")).
+		AddExtend(NewExtend(FieldOptions).
+			AddField(NewMessageField(String, "tag", 50000))).
+		AddExtend(NewExtend(FieldOptions).
+			AddField(NewMessageField(Int32, "weight", 50001))).
+		AddService(NewService("S1"))
+
+	actual, err := Merge(f1, f2)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %+v, want %+v", actual, expected)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	table := []struct {
+		in       []*File
+		expected []string
+	}{
+		{
+			in: []*File{
+				NewFile("org.foo").
+					AddMessage(NewMessage("M1").
+						AddField(NewMessageField(String, "name", 1))).
+					AddOption(NewOption("java_package", `"org.foo"`)),
+				NewFile("org.foo").
+					AddMessage(NewMessage("M1").
+						AddField(NewMessageField(Int32, "name", 1))).
+					AddOption(NewOption("java_package", `"org.bar"`)),
+			},
+			expected: []string{"option java_package", "M1"},
+		},
+		// a message and an enum of the same name
+		{
+			in: []*File{
+				NewFile("org.foo").AddMessage(NewMessage("T1")),
+				NewFile("org.foo").AddEnum(NewEnum("T1")),
+			},
+			expected: []string{"T1"},
+		},
+		// extension fields of the same name or number
+		{
+			in: []*File{
+				NewFile("org.foo").
+					AddExtend(NewExtend(FieldOptions).
+						AddField(NewMessageField(String, "tag", 50000))),
+				NewFile("org.foo").
+					AddExtend(NewExtend(FieldOptions).
+						AddField(NewMessageField(Int32, "tag", 50000)).
+						AddField(NewMessageField(String, "label", 50000))),
+			},
+			expected: []string{
+				"extend google.protobuf.FieldOptions.tag",
+				"extend google.protobuf.FieldOptions = 50000",
+			},
+		},
+	}
+
+	for x, d := range table {
+		_, err := Merge(d.in...)
+		conflict, ok := err.(*ConflictError)
+		if !ok {
+			t.Errorf("#%d: got %v, want *ConflictError", x, err)
+			continue
+		}
+		if !reflect.DeepEqual(conflict.Names, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, conflict.Names, d.expected)
+		}
+	}
+
+	if _, err := Merge(NewFile("org.foo"), NewFile("org.bar")); err == nil {
+		t.Errorf("got no error for different packages, want error")
+	}
+}
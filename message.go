@@ -1,12 +1,23 @@
 package pbast
 
+import "fmt"
+
 type Message struct {
-	Name     string
-	Comment  Comment
-	Fields   []*MessageField
-	Enums    []*Enum
-	Messages []*Message
-	OneOfs   []*OneOf
+	Name       string            `json:"name"`
+	Comment    Comment           `json:"comment,omitempty"`
+	Options    []*Option         `json:"options,omitempty"`
+	Fields     []*MessageField   `json:"fields,omitempty"`
+	Maps       []*MapField       `json:"maps,omitempty"`
+	Enums      []*Enum           `json:"enums,omitempty"`
+	Messages   []*Message        `json:"messages,omitempty"`
+	OneOfs     []*OneOf          `json:"oneOfs,omitempty"`
+	Extensions []*ExtensionRange `json:"extensions,omitempty"`
+	Extends    []*Extend         `json:"extends,omitempty"`
+	Groups     []*Group          `json:"groups,omitempty"`
+	// Reserved holds the field numbers and ReservedNames the field names
+	// which can't be used, typically those of removed fields
+	Reserved      []*ReservedRange `json:"reserved,omitempty"`
+	ReservedNames []string         `json:"reservedNames,omitempty"`
 }
 
 func NewMessage(name string) *Message {
@@ -15,6 +26,14 @@ func NewMessage(name string) *Message {
 	}
 }
 
+func (m *Message) AddOption(o *Option) *Message {
+	if o == nil {
+		return m
+	}
+	m.Options = append(m.Options, o)
+	return m
+}
+
 func (m *Message) AddField(f *MessageField) *Message {
 	if f == nil {
 		return m
@@ -23,6 +42,14 @@ func (m *Message) AddField(f *MessageField) *Message {
 	return m
 }
 
+func (m *Message) AddMapField(f *MapField) *Message {
+	if f == nil {
+		return m
+	}
+	m.Maps = append(m.Maps, f)
+	return m
+}
+
 func (m *Message) AddEnum(e *Enum) *Message {
 	if e == nil {
 		return m
@@ -47,6 +74,56 @@ func (m *Message) AddOneOf(o *OneOf) *Message {
 	return m
 }
 
+func (m *Message) AddExtensionRange(r *ExtensionRange) *Message {
+	if r == nil {
+		return m
+	}
+	m.Extensions = append(m.Extensions, r)
+	return m
+}
+
+func (m *Message) AddReservedRange(r *ReservedRange) *Message {
+	if r == nil {
+		return m
+	}
+	m.Reserved = append(m.Reserved, r)
+	return m
+}
+
+func (m *Message) AddReservedName(name string) *Message {
+	if name == "" {
+		return m
+	}
+	m.ReservedNames = append(m.ReservedNames, name)
+	return m
+}
+
+// IsReserved reports whether the field number is reserved in the message
+func (m *Message) IsReserved(index int) bool {
+	for _, r := range m.Reserved {
+		if r.Start <= index && index <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Message) AddExtend(e *Extend) *Message {
+	if e == nil {
+		return m
+	}
+	m.Extends = append(m.Extends, e)
+	return m
+}
+
+func (m *Message) AddGroup(g *Group) *Message {
+	if g == nil {
+		return m
+	}
+	m.Groups = append(m.Groups, g)
+	return m
+}
+
 func (m *Message) AddType(t Type) {
 	if t == nil {
 		return
@@ -62,18 +139,57 @@ func (m *Message) AddType(t Type) {
 	}
 }
 
+// ValidateFieldNumbers returns an error when two fields in the message,
+// including members of oneofs, have the same field number
+func (m *Message) ValidateFieldNumbers() error {
+	names := map[int]string{}
+	check := func(name string, index int) error {
+		if other, ok := names[index]; ok {
+			return fmt.Errorf("field number %d of %s collides with %s in message %s", index, name, other, m.Name)
+		}
+		names[index] = name
+		return nil
+	}
+
+	for _, f := range m.Fields {
+		if err := check(f.Name, f.Index); err != nil {
+			return err
+		}
+	}
+	for _, f := range m.Maps {
+		if err := check(f.Name, f.Index); err != nil {
+			return err
+		}
+	}
+	for _, o := range m.OneOfs {
+		for _, f := range o.Fields {
+			if err := check(f.Name, f.Index); err != nil {
+				return err
+			}
+		}
+	}
+	for _, g := range m.Groups {
+		if err := check(g.Name, g.Index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type MessageField struct {
-	Repeated bool
-	Type     string
-	Name     string
-	Index    int
-	Options  []*FieldOption
-	Comment  Comment
+	Repeated bool `json:"repeated,omitempty"`
+	// Optional gives the field explicit presence, which proto3 allows since v3.15
+	Optional bool           `json:"optional,omitempty"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`
+	Index    int            `json:"index"`
+	Options  []*FieldOption `json:"options,omitempty"`
+	Comment  Comment        `json:"comment,omitempty"`
 }
 
 type FieldOption struct {
-	Name  string
-	Value string
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 func NewMessageField(t Type, name string, index int) *MessageField {
@@ -94,6 +210,15 @@ func NewRepeatedMessageField(t Type, name string, index int) *MessageField {
 	}
 }
 
+func NewOptionalMessageField(t Type, name string, index int) *MessageField {
+	return &MessageField{
+		Optional: true,
+		Type:     t.TypeName(),
+		Name:     name,
+		Index:    index,
+	}
+}
+
 func NewFieldOption(name, value string) *FieldOption {
 	return &FieldOption{
 		Name:  name,
@@ -108,3 +233,96 @@ func (f *MessageField) AddOption(o *FieldOption) *MessageField {
 	f.Options = append(f.Options, o)
 	return f
 }
+
+type MapField struct {
+	KeyType   string         `json:"keyType"`
+	ValueType string         `json:"valueType"`
+	Name      string         `json:"name"`
+	Index     int            `json:"index"`
+	Options   []*FieldOption `json:"options,omitempty"`
+	Comment   Comment        `json:"comment,omitempty"`
+}
+
+func NewMapField(key, value Type, name string, index int) *MapField {
+	return &MapField{
+		KeyType:   key.TypeName(),
+		ValueType: value.TypeName(),
+		Name:      name,
+		Index:     index,
+	}
+}
+
+func (f *MapField) AddOption(o *FieldOption) *MapField {
+	if o == nil {
+		return f
+	}
+	f.Options = append(f.Options, o)
+	return f
+}
+
+// Validate returns an error when the key type is not allowed for map keys
+func (f *MapField) Validate() error {
+	if !IsValidMapKeyType(BuiltinType(f.KeyType)) {
+		return fmt.Errorf("%s is not allowed as a key type of map %s", f.KeyType, f.Name)
+	}
+	return nil
+}
+
+// IsValidMapKeyType returns whether the type can be used as a key type of maps.
+// Any integral or string type is allowed, but floating point types,
+// bytes, enums and messages are not.
+func IsValidMapKeyType(t Type) bool {
+	switch t {
+	case Int32, Int64, UInt32, UInt64, SInt32, SInt64,
+		Fixed32, Fixed64, SFixed32, SFixed64, Bool, String:
+		return true
+	default:
+		return false
+	}
+}
+
+// Group is a legacy proto2 group, which declares a nested message and
+// a field of it at once. It isn't allowed in proto3.
+type Group struct {
+	Repeated bool            `json:"repeated,omitempty"`
+	Name     string          `json:"name"`
+	Index    int             `json:"index"`
+	Comment  Comment         `json:"comment,omitempty"`
+	Fields   []*MessageField `json:"fields,omitempty"`
+}
+
+func NewGroup(name string, index int) *Group {
+	return &Group{
+		Name:  name,
+		Index: index,
+	}
+}
+
+func NewRepeatedGroup(name string, index int) *Group {
+	return &Group{
+		Repeated: true,
+		Name:     name,
+		Index:    index,
+	}
+}
+
+func (g *Group) AddField(f *MessageField) *Group {
+	if f == nil {
+		return g
+	}
+	g.Fields = append(g.Fields, f)
+	return g
+}
+
+// ReservedRange is a range of reserved field numbers including both ends
+type ReservedRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func NewReservedRange(start, end int) *ReservedRange {
+	return &ReservedRange{
+		Start: start,
+		End:   end,
+	}
+}
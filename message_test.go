@@ -0,0 +1,62 @@
+package pbast
+
+import (
+	"testing"
+)
+
+func TestValidateFieldNumbers(t *testing.T) {
+	table := []struct {
+		in    *Message
+		valid bool
+	}{
+		{
+			in: NewMessage("M1").
+				AddField(NewMessageField(String, "f1", 1)).
+				AddOneOf(NewOneOf("o1").
+					AddField(NewOneOfField(String, "f2", 2))),
+			valid: true,
+		},
+		// oneof member collides with a field
+		{
+			in: NewMessage("M1").
+				AddField(NewMessageField(String, "f1", 1)).
+				AddOneOf(NewOneOf("o1").
+					AddField(NewOneOfField(String, "f2", 1))),
+			valid: false,
+		},
+		// members of different oneofs collide
+		{
+			in: NewMessage("M1").
+				AddOneOf(NewOneOf("o1").
+					AddField(NewOneOfField(String, "f1", 1))).
+				AddOneOf(NewOneOf("o2").
+					AddField(NewOneOfField(String, "f2", 1))),
+			valid: false,
+		},
+	}
+
+	for x, d := range table {
+		if err := d.in.ValidateFieldNumbers(); (err == nil) != d.valid {
+			t.Errorf("#%d: got %v, want valid = %t", x, err, d.valid)
+		}
+	}
+}
+
+func TestMapFieldValidate(t *testing.T) {
+	table := []struct {
+		in    *MapField
+		valid bool
+	}{
+		{NewMapField(String, NewMessage("M1"), "f1", 1), true},
+		{NewMapField(SFixed64, Bytes, "f1", 1), true},
+		{NewMapField(Double, String, "f1", 1), false},
+		{NewMapField(Bytes, String, "f1", 1), false},
+		{NewMapField(NewEnum("E1"), String, "f1", 1), false},
+	}
+
+	for x, d := range table {
+		if err := d.in.Validate(); (err == nil) != d.valid {
+			t.Errorf("#%d: got %v, want valid = %t", x, err, d.valid)
+		}
+	}
+}
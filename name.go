@@ -0,0 +1,18 @@
+package pbast
+
+import "strings"
+
+// BaseName returns the last component of the dotted full name
+func BaseName(fullName string) string {
+	return fullName[strings.LastIndex(fullName, ".")+1:]
+}
+
+// ParentName returns the full name of the scope enclosing the dotted full name,
+// or "" when the name is not nested
+func ParentName(fullName string) string {
+	i := strings.LastIndex(fullName, ".")
+	if i < 0 {
+		return ""
+	}
+	return fullName[:i]
+}
@@ -33,6 +33,14 @@ func (f *MessageField) name() string {
 	return "messageField"
 }
 
+func (f *MapField) name() string {
+	return "mapField"
+}
+
+func (g *Group) name() string {
+	return "group"
+}
+
 func (o *FieldOption) name() string {
 	return "fieldOption"
 }
@@ -57,6 +65,18 @@ func (e *EnumValueOption) name() string {
 	return "enumValueOption"
 }
 
+func (r *ExtensionRange) name() string {
+	return "extensionRange"
+}
+
+func (r *ReservedRange) name() string {
+	return "reservedRange"
+}
+
+func (e *Extend) name() string {
+	return "extend"
+}
+
 func (s *Service) name() string {
 	return "service"
 }
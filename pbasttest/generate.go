@@ -0,0 +1,168 @@
+package pbasttest
+
+import (
+	"fmt"
+
+	"github.com/oshothebig/pbast"
+)
+
+// names are picked for declarations. Keywords and the first component of
+// the package are included to exercise renaming passes.
+var names = []string{
+	"Config", "State", "Interface", "Entry", "org", "syntax",
+	"message", "string", "Value", "Status", "Counters", "Key",
+}
+
+var builtins = []pbast.BuiltinType{
+	pbast.String, pbast.Int32, pbast.UInt64, pbast.Bool, pbast.Bytes, pbast.Double,
+}
+
+// source yields bytes of the input one by one, and zeros after the end
+type source struct {
+	data []byte
+}
+
+func (s *source) next(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	if len(s.data) == 0 {
+		return 0
+	}
+	b := s.data[0]
+	s.data = s.data[1:]
+	return int(b) % n
+}
+
+// NewFile builds a valid file from arbitrary bytes, e.g. fuzzing input.
+// The same input always yields the same file. In the file, names are unique
+// in each scope, field numbers are unique in each message and every type
+// reference resolves to a type declared in the file or to a builtin type.
+func NewFile(data []byte) *pbast.File {
+	g := &generator{src: &source{data: data}}
+	f := pbast.NewFile("org.foo")
+
+	scope := map[string]bool{}
+	for i, n := 0, g.src.next(4)+1; i < n; i++ {
+		f.AddMessage(g.message("", g.name(scope), 0))
+	}
+	for i, n := 0, g.src.next(3); i < n; i++ {
+		f.AddEnum(g.enum("", g.name(scope)))
+	}
+
+	// fields are filled after all types are declared to allow forward references
+	for _, m := range f.Messages {
+		g.fill(m)
+	}
+
+	for i, n := 0, g.src.next(2); i < n; i++ {
+		s := pbast.NewService(g.name(scope))
+		rpcs := map[string]bool{}
+		for j, k := 0, g.src.next(3)+1; j < k; j++ {
+			s.AddRPC(pbast.NewRPC(g.name(rpcs), g.returnType(), g.returnType()))
+		}
+		f.AddService(s)
+	}
+	return f
+}
+
+type generator struct {
+	src *source
+	// types holds the full names of the declared messages and enums
+	types []string
+	// messages holds the full names of the declared messages
+	messages []string
+}
+
+// name returns a name unique in the scope
+func (g *generator) name(scope map[string]bool) string {
+	name := names[g.src.next(len(names))]
+	for i := 2; scope[name]; i++ {
+		name = fmt.Sprintf("%s%d", names[g.src.next(len(names))], i)
+	}
+	scope[name] = true
+	return name
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (g *generator) message(scope, name string, depth int) *pbast.Message {
+	m := pbast.NewMessage(name)
+	fullName := qualify(scope, name)
+	g.messages = append(g.messages, fullName)
+	g.types = append(g.types, fullName)
+	if depth >= 2 {
+		return m
+	}
+
+	inner := map[string]bool{}
+	for i, n := 0, g.src.next(3); i < n; i++ {
+		m.AddMessage(g.message(fullName, g.name(inner), depth+1))
+	}
+	for i, n := 0, g.src.next(2); i < n; i++ {
+		m.AddEnum(g.enum(fullName, g.name(inner)))
+	}
+	return m
+}
+
+func (g *generator) enum(scope, name string) *pbast.Enum {
+	e := pbast.NewEnum(name)
+	g.types = append(g.types, qualify(scope, name))
+	for i, n := 0, g.src.next(4)+1; i < n; i++ {
+		e.AddField(pbast.NewEnumField(fmt.Sprintf("%s_%d", name, i), i))
+	}
+	return e
+}
+
+// fill adds fields to the message and its nested messages
+func (g *generator) fill(m *pbast.Message) {
+	scope := map[string]bool{}
+	for _, n := range m.Messages {
+		scope[n.Name] = true
+	}
+	for _, e := range m.Enums {
+		scope[e.Name] = true
+	}
+
+	index := 1
+	for i, n := 0, g.src.next(5); i < n; i++ {
+		switch g.src.next(4) {
+		case 0:
+			m.AddField(pbast.NewRepeatedMessageField(g.fieldType(), g.name(scope), index))
+		case 1:
+			m.AddMapField(pbast.NewMapField(pbast.String, g.fieldType(), g.name(scope), index))
+		default:
+			m.AddField(pbast.NewMessageField(g.fieldType(), g.name(scope), index))
+		}
+		index++
+	}
+	if g.src.next(2) == 1 {
+		o := pbast.NewOneOf(g.name(scope))
+		for i, n := 0, g.src.next(3)+1; i < n; i++ {
+			o.AddField(pbast.NewOneOfField(g.fieldType(), g.name(scope), index))
+			index++
+		}
+		m.AddOneOf(o)
+	}
+
+	for _, n := range m.Messages {
+		g.fill(n)
+	}
+}
+
+// fieldType returns a builtin type or a fully qualified reference to a declared type
+func (g *generator) fieldType() pbast.Type {
+	if g.src.next(2) == 0 {
+		return builtins[g.src.next(len(builtins))]
+	}
+	return pbast.NewMessage(".org.foo." + g.types[g.src.next(len(g.types))])
+}
+
+func (g *generator) returnType() *pbast.ReturnType {
+	return pbast.NewReturnType(".org.foo." + g.messages[g.src.next(len(g.messages))])
+}
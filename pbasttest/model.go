@@ -0,0 +1,50 @@
+package pbasttest
+
+import (
+	"fmt"
+
+	"github.com/oshothebig/pbast"
+)
+
+// NewModel builds a file shaped like the ones generated from large YANG models
+// such as openconfig, to benchmark passes. Each of the n containers nests
+// Config and State messages of the same structure as the other containers,
+// a list of entries and a field named after a keyword. The same n always
+// yields the same file.
+func NewModel(n int) *pbast.File {
+	f := pbast.NewFile("org.foo").
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("STATUS_UNKNOWN", 0)).
+			AddField(pbast.NewEnumField("STATUS_UP", 1)).
+			AddField(pbast.NewEnumField("STATUS_DOWN", 2)))
+	s := pbast.NewService("Model")
+
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("Container%d", i)
+		f.AddMessage(pbast.NewMessage(name).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("State"), "state", 2)).
+			AddField(pbast.NewRepeatedMessageField(pbast.NewMessage("Entry"), "entries", 3)).
+			AddField(pbast.NewMessageField(pbast.String, "message", 4)).
+			AddMessage(modelConfig("Config")).
+			AddMessage(modelConfig("State").
+				AddField(pbast.NewMessageField(pbast.NewMessage("Counters"), "counters", 5)).
+				AddMessage(pbast.NewMessage("Counters").
+					AddField(pbast.NewMessageField(pbast.UInt64, "in_octets", 1)).
+					AddField(pbast.NewMessageField(pbast.UInt64, "out_octets", 2)))).
+			AddMessage(pbast.NewMessage("Entry").
+				AddField(pbast.NewMessageField(pbast.String, "key", 1)).
+				AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 2)).
+				AddMapField(pbast.NewMapField(pbast.String, pbast.String, "labels", 3))))
+		s.AddRPC(pbast.NewRPC("Get"+name, pbast.NewReturnType(name), pbast.NewReturnType(name)))
+	}
+	return f.AddService(s)
+}
+
+func modelConfig(name string) *pbast.Message {
+	return pbast.NewMessage(name).
+		AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+		AddField(pbast.NewMessageField(pbast.UInt32, "mtu", 2)).
+		AddField(pbast.NewMessageField(pbast.Bool, "enabled", 3)).
+		AddField(pbast.NewMessageField(pbast.NewEnum("Status"), "status", 4))
+}
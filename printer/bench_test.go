@@ -0,0 +1,15 @@
+package printer
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/oshothebig/pbast/pbasttest"
+)
+
+func BenchmarkFprint(b *testing.B) {
+	f := pbasttest.NewModel(200)
+	for i := 0; i < b.N; i++ {
+		Fprint(ioutil.Discard, f)
+	}
+}
@@ -0,0 +1,47 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+)
+
+// Header holds the variables available in header templates
+type Header struct {
+	// File is the name of the file the header is written to
+	File     string
+	Module   string
+	Revision string
+	Year     int
+}
+
+// HeaderTemplate returns the post processor writing the header rendered by
+// the template, e.g. a license, as line comments at the top of each file.
+// data returns the variables for the file of the name; File is set by the processor.
+func HeaderTemplate(text string, data func(name string) Header) (PostProcessor, error) {
+	tmpl, err := template.New("header").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return PostProcessorFunc(func(name string, body []byte) ([]byte, error) {
+		h := data(name)
+		h.File = name
+		rendered := new(bytes.Buffer)
+		if err := tmpl.Execute(rendered, h); err != nil {
+			return nil, err
+		}
+
+		buf := new(bytes.Buffer)
+		for _, line := range strings.Split(strings.TrimRight(rendered.String(), "\n"), "\n") {
+			if line == "" {
+				buf.WriteString("//\n")
+			} else {
+				buf.WriteString("// " + line + "\n")
+			}
+		}
+		buf.WriteString("\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}), nil
+}
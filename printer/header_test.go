@@ -0,0 +1,34 @@
+package printer
+
+import "testing"
+
+func TestHeaderTemplate(t *testing.T) {
+	text := `Copyright {{.Year}} The Authors.
+
+Generated from {{.Module}}@{{.Revision}} into {{.File}}.
+`
+	p, err := HeaderTemplate(text, func(name string) Header {
+		return Header{Module: "openconfig-bgp", Revision: "2017-02-02", Year: 2017}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual, err := p.Process("bgp.proto", []byte("syntax = \"proto3\";\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `// Copyright 2017 The Authors.
+//
+// Generated from openconfig-bgp@2017-02-02 into bgp.proto.
+
+syntax = "proto3";
+`
+	if string(actual) != expected {
+		t.Errorf("got %q, want %q", actual, expected)
+	}
+
+	if _, err := HeaderTemplate("{{.Year", nil); err == nil {
+		t.Error("expected an error for the malformed template")
+	}
+}
@@ -0,0 +1,95 @@
+package printer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/oshothebig/pbast"
+)
+
+// Output receives the printed files.
+// Names are slash-separated paths relative to the root of the output.
+type Output interface {
+	WriteFile(name string, data []byte) error
+}
+
+// WriteFiles prints the files and writes them to the output in the order of their names
+func WriteFiles(out Output, files map[string]*pbast.File) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf := new(bytes.Buffer)
+		Fprint(buf, files[name])
+		if err := out.WriteFile(name, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DirOutput writes files under the directory, creating subdirectories as needed
+type DirOutput string
+
+func (d DirOutput) WriteFile(name string, data []byte) error {
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ReadFile reads the file of the name written to the directory
+func (d DirOutput) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// MapOutput holds files in memory keyed by their names
+type MapOutput map[string][]byte
+
+func (m MapOutput) WriteFile(name string, data []byte) error {
+	m[name] = data
+	return nil
+}
+
+// ZipOutput writes files into a zip archive.
+// The caller closes the archive after writing all files.
+type ZipOutput struct {
+	*zip.Writer
+}
+
+func (z ZipOutput) WriteFile(name string, data []byte) error {
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// TarOutput writes files into a tar stream.
+// The caller closes the stream after writing all files.
+type TarOutput struct {
+	*tar.Writer
+}
+
+func (t TarOutput) WriteFile(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(data)),
+	}
+	if err := t.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := t.Write(data)
+	return err
+}
@@ -0,0 +1,72 @@
+package printer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestWriteFiles(t *testing.T) {
+	files := map[string]*pbast.File{
+		"org/foo/b.proto": pbast.NewFile("org.foo").AddMessage(pbast.NewMessage("B")),
+		"org/foo/a.proto": pbast.NewFile("org.foo").AddEnum(pbast.NewEnum("A")),
+	}
+	expected := MapOutput{}
+	for name, f := range files {
+		buf := new(bytes.Buffer)
+		Fprint(buf, f)
+		expected[name] = buf.Bytes()
+	}
+
+	m := MapOutput{}
+	if err := WriteFiles(m, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("got %q, want %q", m, expected)
+	}
+
+	dir, err := ioutil.TempDir("", "printer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := WriteFiles(DirOutput(dir), files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, data := range expected {
+		actual, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(actual, data) {
+			t.Errorf("%s: got %q, want %q", name, actual, data)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	if err := WriteFiles(ZipOutput{zw}, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if expectedNames := []string{"org/foo/a.proto", "org/foo/b.proto"}; !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("got %v, want %v", names, expectedNames)
+	}
+}
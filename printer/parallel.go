@@ -0,0 +1,75 @@
+package printer
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/oshothebig/pbast"
+)
+
+// WriteFilesParallel is WriteFiles printing the files with the number of
+// workers concurrently, or GOMAXPROCS workers when workers is not positive.
+// The files are still written to the output one by one in the order of their
+// names, so any Output can be used, and the first error in that order is returned.
+// Printed files waiting to be written are limited to twice the number of workers.
+func WriteFilesParallel(out Output, files map[string]*pbast.File, workers int) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]chan []byte, len(names))
+	for i := range results {
+		results[i] = make(chan []byte, 1)
+	}
+	pending := make(chan struct{}, 2*workers)
+	jobs := make(chan int)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for i := range names {
+			select {
+			case pending <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				buf := new(bytes.Buffer)
+				Fprint(buf, files[names[i]])
+				results[i] <- buf.Bytes()
+			}
+		}()
+	}
+
+	var err error
+	for i, name := range names {
+		if err = out.WriteFile(name, <-results[i]); err != nil {
+			break
+		}
+		<-pending
+	}
+	close(done)
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,62 @@
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+type failingOutput struct {
+	MapOutput
+	fail    string
+	written []string
+}
+
+func (o *failingOutput) WriteFile(name string, data []byte) error {
+	if name == o.fail {
+		return errors.New("failed to write " + name)
+	}
+	o.written = append(o.written, name)
+	return o.MapOutput.WriteFile(name, data)
+}
+
+func TestWriteFilesParallel(t *testing.T) {
+	files := map[string]*pbast.File{}
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("foo/%02d.proto", i)] = pbast.NewFile("foo").
+			AddMessage(pbast.NewMessage(fmt.Sprintf("M%d", i)))
+	}
+
+	expected := MapOutput{}
+	if err := WriteFiles(expected, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, workers := range []int{0, 1, 4} {
+		actual := &failingOutput{MapOutput: MapOutput{}}
+		if err := WriteFilesParallel(actual, files, workers); err != nil {
+			t.Fatalf("%d workers: unexpected error: %v", workers, err)
+		}
+		if !reflect.DeepEqual(actual.MapOutput, expected) {
+			t.Errorf("%d workers: got %q, want %q", workers, actual.MapOutput, expected)
+		}
+		for i, name := range actual.written {
+			if name != fmt.Sprintf("foo/%02d.proto", i) {
+				t.Errorf("%d workers: %s is written at %d", workers, name, i)
+				break
+			}
+		}
+
+		failing := &failingOutput{MapOutput: MapOutput{}, fail: "foo/10.proto"}
+		err := WriteFilesParallel(failing, files, workers)
+		if err == nil || err.Error() != "failed to write foo/10.proto" {
+			t.Errorf("%d workers: got %v, want the error of foo/10.proto", workers, err)
+		}
+		if len(failing.written) != 10 {
+			t.Errorf("%d workers: %d files are written before the error, want 10", workers, len(failing.written))
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package printer
+
+import "fmt"
+
+// PostProcessor rewrites the printed text of a file before it is written,
+// e.g. to reformat it or to stamp a license header
+type PostProcessor interface {
+	Process(name string, data []byte) ([]byte, error)
+}
+
+// PostProcessorFunc is a function used as a PostProcessor
+type PostProcessorFunc func(name string, data []byte) ([]byte, error)
+
+func (fn PostProcessorFunc) Process(name string, data []byte) ([]byte, error) {
+	return fn(name, data)
+}
+
+type postProcessedOutput struct {
+	out        Output
+	processors []PostProcessor
+}
+
+// PostProcess returns the output running the processors in order
+// over each file before writing it to out
+func PostProcess(out Output, processors ...PostProcessor) Output {
+	return &postProcessedOutput{out, processors}
+}
+
+func (o *postProcessedOutput) WriteFile(name string, data []byte) error {
+	for _, p := range o.processors {
+		var err error
+		data, err = p.Process(name, data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return o.out.WriteFile(name, data)
+}
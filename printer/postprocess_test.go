@@ -0,0 +1,44 @@
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestPostProcess(t *testing.T) {
+	files := map[string]*pbast.File{
+		"foo.proto": pbast.NewFile("foo").AddMessage(pbast.NewMessage("Foo")),
+	}
+	header := PostProcessorFunc(func(name string, data []byte) ([]byte, error) {
+		return append([]byte("// "+name+"\n"), data...), nil
+	})
+	trim := PostProcessorFunc(func(name string, data []byte) ([]byte, error) {
+		return bytes.TrimRight(data, "\n"), nil
+	})
+
+	buf := new(bytes.Buffer)
+	Fprint(buf, files["foo.proto"])
+	expected := "// foo.proto\n" + string(bytes.TrimRight(buf.Bytes(), "\n"))
+
+	m := MapOutput{}
+	if err := WriteFiles(PostProcess(m, header, trim), files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := string(m["foo.proto"]); actual != expected {
+		t.Errorf("got %q, want %q", actual, expected)
+	}
+
+	fail := PostProcessorFunc(func(name string, data []byte) ([]byte, error) {
+		return nil, errors.New("failed")
+	})
+	m = MapOutput{}
+	if err := WriteFiles(PostProcess(m, fail), files); err == nil {
+		t.Error("expected an error from the post processor")
+	}
+	if len(m) != 0 {
+		t.Errorf("files are written on error: %v", m)
+	}
+}
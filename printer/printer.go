@@ -1,6 +1,7 @@
 package printer
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"strings"
@@ -16,6 +17,27 @@ func Fprint(w io.Writer, n pbast.Node) {
 	p.Fprint(w, n)
 }
 
+// Write prints the node to w through a buffer, flushed as it fills up, and
+// returns the number of bytes written to w with the first error writing to it
+func Write(w io.Writer, n pbast.Node) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	Fprint(bw, n)
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
 func (p *printer) Fprint(w io.Writer, n pbast.Node) {
 	switch n := n.(type) {
 	case *pbast.File:
@@ -32,6 +54,10 @@ func (p *printer) Fprint(w io.Writer, n pbast.Node) {
 		p.printMessage(w, n)
 	case *pbast.MessageField:
 		p.printMessageField(w, n)
+	case *pbast.MapField:
+		p.printMapField(w, n)
+	case *pbast.Group:
+		p.printGroup(w, n)
 	case *pbast.OneOf:
 		p.printOneOf(w, n)
 	case *pbast.OneOfField:
@@ -44,6 +70,12 @@ func (p *printer) Fprint(w io.Writer, n pbast.Node) {
 		p.printEnumField(w, n)
 	case *pbast.EnumValueOption:
 		p.printEnumValueOption(w, n)
+	case *pbast.ExtensionRange:
+		p.printExtensionRange(w, n)
+	case *pbast.ReservedRange:
+		p.printReservedRange(w, n)
+	case *pbast.Extend:
+		p.printExtend(w, n)
 	case *pbast.Service:
 		p.printService(w, n)
 	case *pbast.RPC:
@@ -70,7 +102,7 @@ func (p *printer) printFile(w io.Writer, f *pbast.File) {
 	}
 	// options
 	for _, o := range f.Options {
-		p.Fprint(w, o)
+		p.printOptionStatement(w, o)
 	}
 	// messages
 	for _, m := range f.Messages {
@@ -82,6 +114,11 @@ func (p *printer) printFile(w io.Writer, f *pbast.File) {
 		fmt.Fprintln(w)
 		p.Fprint(w, e)
 	}
+	// extends
+	for _, e := range f.Extends {
+		fmt.Fprintln(w)
+		p.Fprint(w, e)
+	}
 	// services
 	for _, s := range f.Services {
 		fmt.Fprintln(w)
@@ -114,6 +151,13 @@ func (p *printer) printOption(w io.Writer, o *pbast.Option) {
 	fmt.Fprintln(w)
 }
 
+// printOptionStatement prints an option declared in a file, message, oneof, service or RPC.
+// Options of fields are printed in brackets instead.
+func (p *printer) printOptionStatement(w io.Writer, o *pbast.Option) {
+	fmt.Fprint(w, "option ")
+	p.Fprint(w, o)
+}
+
 func (p *printer) printMessage(w io.Writer, m *pbast.Message) {
 	// comment
 	p.Fprint(w, m.Comment)
@@ -123,10 +167,26 @@ func (p *printer) printMessage(w io.Writer, m *pbast.Message) {
 	fmt.Fprintln(w)
 
 	indent := pbast.NewSpaceWriter(w, shift)
+	// options
+	for _, o := range m.Options {
+		p.printOptionStatement(indent, o)
+	}
+	// reserved
+	for _, r := range m.Reserved {
+		p.Fprint(indent, r)
+	}
+	for _, name := range m.ReservedNames {
+		fmt.Fprintf(indent, "reserved %q;", name)
+		fmt.Fprintln(indent)
+	}
 	// fields
 	for _, f := range m.Fields {
 		p.Fprint(indent, f)
 	}
+	// maps
+	for _, f := range m.Maps {
+		p.Fprint(indent, f)
+	}
 	// enums
 	for _, e := range m.Enums {
 		p.Fprint(indent, e)
@@ -139,6 +199,18 @@ func (p *printer) printMessage(w io.Writer, m *pbast.Message) {
 	for _, o := range m.OneOfs {
 		p.Fprint(indent, o)
 	}
+	// extension ranges
+	for _, r := range m.Extensions {
+		p.Fprint(indent, r)
+	}
+	// extends
+	for _, e := range m.Extends {
+		p.Fprint(indent, e)
+	}
+	// groups
+	for _, g := range m.Groups {
+		p.Fprint(indent, g)
+	}
 
 	fmt.Fprintf(w, "}")
 	fmt.Fprintln(w)
@@ -151,6 +223,9 @@ func (p *printer) printMessageField(w io.Writer, f *pbast.MessageField) {
 	if f.Repeated {
 		fmt.Fprintf(w, "repeated ")
 	}
+	if f.Optional {
+		fmt.Fprintf(w, "optional ")
+	}
 	fmt.Fprintf(w, "%s %s = %d", f.Type, f.Name, f.Index)
 
 	if len(f.Options) > 0 {
@@ -167,6 +242,48 @@ func (p *printer) printMessageField(w io.Writer, f *pbast.MessageField) {
 	fmt.Fprintln(w)
 }
 
+func (p *printer) printMapField(w io.Writer, f *pbast.MapField) {
+	// comment
+	p.printComment(w, f.Comment)
+
+	fmt.Fprintf(w, "map<%s, %s> %s = %d", f.KeyType, f.ValueType, f.Name, f.Index)
+
+	if len(f.Options) > 0 {
+		fmt.Fprint(w, " [")
+		p.Fprint(w, f.Options[0])
+
+		for _, f := range f.Options[1:] {
+			fmt.Fprint(w, ", ")
+			p.Fprint(w, f)
+		}
+		fmt.Fprint(w, "]")
+	}
+	fmt.Fprint(w, ";")
+	fmt.Fprintln(w)
+}
+
+func (p *printer) printGroup(w io.Writer, g *pbast.Group) {
+	// comment
+	p.printComment(w, g.Comment)
+
+	if g.Repeated {
+		fmt.Fprint(w, "repeated ")
+	} else {
+		fmt.Fprint(w, "optional ")
+	}
+	fmt.Fprintf(w, "group %s = %d {", g.Name, g.Index)
+	fmt.Fprintln(w)
+
+	indent := pbast.NewSpaceWriter(w, shift)
+	// fields
+	for _, f := range g.Fields {
+		p.Fprint(indent, f)
+	}
+
+	fmt.Fprintf(w, "}")
+	fmt.Fprintln(w)
+}
+
 func (p *printer) printOneOf(w io.Writer, o *pbast.OneOf) {
 	// comment
 	p.printComment(w, o.Comment)
@@ -176,6 +293,10 @@ func (p *printer) printOneOf(w io.Writer, o *pbast.OneOf) {
 	fmt.Fprintln(w)
 
 	indent := pbast.NewSpaceWriter(w, shift)
+	// options
+	for _, opt := range o.Options {
+		p.printOptionStatement(indent, opt)
+	}
 	// fields
 	for _, f := range o.Fields {
 		p.Fprint(indent, f)
@@ -193,12 +314,11 @@ func (p *printer) printOneOfField(w io.Writer, f *pbast.OneOfField) {
 
 	if len(f.Options) > 0 {
 		fmt.Fprint(w, " [")
-		p.Fprint(w, f.Options[0])
-
-		for _, f := range f.Options[1:] {
-			fmt.Fprint(w, ", ")
-			p.Fprint(w, f)
+		opts := []string{}
+		for _, o := range f.Options {
+			opts = append(opts, fmt.Sprintf("%s = %s", o.Name, o.Value))
 		}
+		fmt.Fprint(w, strings.Join(opts, ", "))
 		fmt.Fprint(w, "]")
 	}
 	fmt.Fprint(w, ";")
@@ -215,9 +335,18 @@ func (p *printer) printEnum(w io.Writer, e *pbast.Enum) {
 	// name
 	fmt.Fprintf(w, "enum %s {", e.Name)
 	fmt.Fprintln(w)
+	indent := pbast.NewSpaceWriter(w, shift)
+	// reserved
+	for _, r := range e.Reserved {
+		p.Fprint(indent, r)
+	}
+	for _, name := range e.ReservedNames {
+		fmt.Fprintf(indent, "reserved %q;", name)
+		fmt.Fprintln(indent)
+	}
 	// fields
 	for _, f := range e.Fields {
-		p.Fprint(pbast.NewSpaceWriter(w, shift), f)
+		p.Fprint(indent, f)
 	}
 	fmt.Fprintf(w, "}")
 	fmt.Fprintln(w)
@@ -244,6 +373,47 @@ func (p *printer) printEnumValueOption(w io.Writer, o *pbast.EnumValueOption) {
 	fmt.Fprintf(w, "%s = %s", o.Name, o.Value)
 }
 
+func (p *printer) printExtensionRange(w io.Writer, r *pbast.ExtensionRange) {
+	switch r.End {
+	case r.Start:
+		fmt.Fprintf(w, "extensions %d;", r.Start)
+	case pbast.MaxFieldNumber:
+		fmt.Fprintf(w, "extensions %d to max;", r.Start)
+	default:
+		fmt.Fprintf(w, "extensions %d to %d;", r.Start, r.End)
+	}
+	fmt.Fprintln(w)
+}
+
+func (p *printer) printReservedRange(w io.Writer, r *pbast.ReservedRange) {
+	switch r.End {
+	case r.Start:
+		fmt.Fprintf(w, "reserved %d;", r.Start)
+	case pbast.MaxFieldNumber:
+		fmt.Fprintf(w, "reserved %d to max;", r.Start)
+	default:
+		fmt.Fprintf(w, "reserved %d to %d;", r.Start, r.End)
+	}
+	fmt.Fprintln(w)
+}
+
+func (p *printer) printExtend(w io.Writer, e *pbast.Extend) {
+	// comment
+	p.Fprint(w, e.Comment)
+
+	fmt.Fprintf(w, "extend %s {", e.Type)
+	fmt.Fprintln(w)
+
+	indent := pbast.NewSpaceWriter(w, shift)
+	// fields
+	for _, f := range e.Fields {
+		p.Fprint(indent, f)
+	}
+
+	fmt.Fprintf(w, "}")
+	fmt.Fprintln(w)
+}
+
 func (p *printer) printService(w io.Writer, s *pbast.Service) {
 	// comment
 	p.Fprint(w, s.Comment)
@@ -253,7 +423,7 @@ func (p *printer) printService(w io.Writer, s *pbast.Service) {
 	indent := pbast.NewSpaceWriter(w, shift)
 	// options
 	for _, o := range s.Options {
-		p.Fprint(indent, o)
+		p.printOptionStatement(indent, o)
 	}
 	// RPCs
 	for _, r := range s.RPCs {
@@ -272,7 +442,27 @@ func (p *printer) printRPC(w io.Writer, r *pbast.RPC) {
 	p.Fprint(w, r.Input)
 	fmt.Fprint(w, " returns ")
 	p.Fprint(w, r.Output)
-	fmt.Fprint(w, ";")
+
+	if r.IdempotencyLevel == pbast.IdempotencyUnknown && len(r.Options) == 0 {
+		fmt.Fprint(w, ";")
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprint(w, " {")
+	fmt.Fprintln(w)
+
+	indent := pbast.NewSpaceWriter(w, shift)
+	// idempotency level
+	if r.IdempotencyLevel != pbast.IdempotencyUnknown {
+		p.printOptionStatement(indent, pbast.NewOption("idempotency_level", r.IdempotencyLevel.String()))
+	}
+	// options
+	for _, o := range r.Options {
+		p.printOptionStatement(indent, o)
+	}
+
+	fmt.Fprintf(w, "}")
 	fmt.Fprintln(w)
 }
 
@@ -2,9 +2,11 @@ package printer
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/pbasttest"
 )
 
 var syntax pbast.Syntax
@@ -109,6 +111,10 @@ message Root {
 		pbast.NewRepeatedMessageField(pbast.String, "name", 0),
 		"repeated string name = 0;\n",
 	},
+	{
+		pbast.NewOptionalMessageField(pbast.NewMessage("Config"), "config", 1),
+		"optional Config config = 1;\n",
+	},
 	{
 		pbast.NewMessageField(pbast.String, "name", 0).AddOption(pbast.NewFieldOption("age", "21")),
 		"string name = 0 [age = 21];\n",
@@ -119,6 +125,51 @@ message Root {
 			AddOption(pbast.NewFieldOption("tall", "170")),
 		"string name = 0 [age = 21, tall = 170];\n",
 	},
+	{
+		pbast.NewMapField(pbast.String, pbast.NewMessage("Project"), "projects", 3),
+		"map<string, Project> projects = 3;\n",
+	},
+	{
+		pbast.NewMessage("human").
+			AddOption(pbast.NewOption("deprecated", "true")).
+			AddField(pbast.NewMessageField(pbast.String, "name", 1).
+				AddOption(pbast.NewFieldOption("deprecated", "true"))),
+		`message human {
+  option deprecated = true;
+  string name = 1 [deprecated = true];
+}
+`,
+	},
+	{
+		pbast.NewMessage("human").
+			AddMapField(pbast.NewMapField(pbast.Int32, pbast.String, "tags", 1).
+				AddOption(pbast.NewFieldOption("deprecated", "true"))),
+		`message human {
+  map<int32, string> tags = 1 [deprecated = true];
+}
+`,
+	},
+	{
+		pbast.NewRepeatedGroup("Result", 1).
+			AddField(pbast.NewMessageField(pbast.String, "url", 2)),
+		`repeated group Result = 1 {
+  string url = 2;
+}
+`,
+	},
+	{
+		pbast.NewMessage("human").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+			AddGroup(pbast.NewGroup("Result", 2).
+				AddField(pbast.NewMessageField(pbast.String, "url", 3))),
+		`message human {
+  string name = 1;
+  optional group Result = 2 {
+    string url = 3;
+  }
+}
+`,
+	},
 	{
 		&pbast.OneOf{
 			Name: "value",
@@ -131,6 +182,68 @@ message Root {
   string string = 1;
   string name = 2;
 }
+`,
+	},
+	{
+		&pbast.OneOf{
+			Name:    "value",
+			Options: []*pbast.Option{pbast.NewOption("deprecated", "true")},
+			Fields: []*pbast.OneOfField{
+				pbast.NewOneOfField(pbast.String, "string", 1).
+					AddOption(pbast.NewOption("deprecated", "true")).
+					AddOption(pbast.NewOption("json_name", `"str"`)),
+			},
+		},
+		`oneof value {
+  option deprecated = true;
+  string string = 1 [deprecated = true, json_name = "str"];
+}
+`,
+	},
+	{
+		pbast.NewMessage("human").
+			AddExtensionRange(pbast.NewExtensionRange(100, 100)).
+			AddExtensionRange(pbast.NewExtensionRange(200, 299)).
+			AddExtensionRange(pbast.NewExtensionRange(1000, pbast.MaxFieldNumber)).
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)),
+		`message human {
+  string name = 1;
+  extensions 100;
+  extensions 200 to 299;
+  extensions 1000 to max;
+}
+`,
+	},
+	{
+		pbast.NewMessage("human").
+			AddReservedRange(pbast.NewReservedRange(2, 2)).
+			AddReservedRange(pbast.NewReservedRange(9, 11)).
+			AddReservedName("age").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)),
+		`message human {
+  reserved 2;
+  reserved 9 to 11;
+  reserved "age";
+  string name = 1;
+}
+`,
+	},
+	{
+		pbast.NewExtend(pbast.FieldOptions).
+			AddField(pbast.NewMessageField(pbast.String, "unit", 50000)),
+		`extend google.protobuf.FieldOptions {
+  string unit = 50000;
+}
+`,
+	},
+	{
+		pbast.NewOneOf("value").
+			AddOption(pbast.NewOption("(foo)", "true")).
+			AddField(pbast.NewOneOfField(pbast.String, "name", 1)),
+		`oneof value {
+  option (foo) = true;
+  string name = 1;
+}
 `,
 	},
 	{
@@ -141,6 +254,18 @@ message Root {
   male = 1;
   female = 2;
 }
+`,
+	},
+	{
+		pbast.NewEnum("sex").
+			AddReservedRange(pbast.NewReservedRange(3, 3)).
+			AddReservedName("other").
+			AddField(pbast.NewEnumField("male", 1)),
+		`enum sex {
+  reserved 3;
+  reserved "other";
+  male = 1;
+}
 `,
 	},
 	{
@@ -174,6 +299,25 @@ message Root {
   rpc name (string) returns (int);
   rpc age (string) returns (int);
 }
+`,
+	},
+	{
+		pbast.NewService("get").
+			AddOptions(pbast.NewOption("deprecated", "true")).
+			AddRPC(pbast.NewRPC("name", pbast.NewReturnType("string"), pbast.NewReturnType("int")).
+				SetClientStreaming(true).
+				SetServerStreaming(true)).
+			AddRPC(pbast.NewRPC("age", pbast.NewReturnType("string"), pbast.NewReturnType("int")).
+				SetIdempotencyLevel(pbast.NoSideEffects).
+				AddOption(pbast.NewOption("(foo.bar)", "1"))),
+		`service get {
+  option deprecated = true;
+  rpc name (stream string) returns (stream int);
+  rpc age (string) returns (int) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+    option (foo.bar) = 1;
+  }
+}
 `,
 	},
 	{
@@ -226,6 +370,40 @@ func TestWrite(t *testing.T) {
 		if bytes.Compare(buf.Bytes(), []byte(d.expected)) != 0 {
 			t.Errorf("#%d:\ngot\n%s\nwant\n%s", x, buf.Bytes(), d.expected)
 		}
+
+		buf.Reset()
+		n, err := Write(buf, d.in)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+		}
+		if buf.String() != d.expected || n != int64(len(d.expected)) {
+			t.Errorf("#%d: got %d bytes %q, want %d bytes %q", x, n, buf.Bytes(), len(d.expected), d.expected)
+		}
+	}
+}
+
+type limitedWriter struct {
+	limit int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errors.New("no space left")
+	}
+	w.limit -= len(b)
+	return len(b), nil
+}
+
+func TestWriteError(t *testing.T) {
+	f := pbasttest.NewModel(100)
+	n, err := Write(&limitedWriter{limit: 5000}, f)
+	if err == nil {
+		t.Fatal("expected an error from the writer")
+	}
+	if n != 5000 {
+		t.Errorf("got %d bytes written, want 5000", n)
 	}
 }
 
@@ -0,0 +1,94 @@
+package printertest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/printer"
+)
+
+// Golden compares the printed node with the content of the golden file
+// at the path and reports the difference as a test error.
+// When update is true, the golden file is overwritten with the output instead.
+func Golden(t testing.TB, path string, n pbast.Node, update bool) {
+	buf := new(bytes.Buffer)
+	printer.Fprint(buf, n)
+
+	if update {
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if d := Diff(string(want), buf.String()); d != "" {
+		t.Errorf("%s: output differs from golden file (-want +got):\n%s", path, d)
+	}
+}
+
+// Diff returns a line-oriented difference between want and got,
+// prefixing removed lines with "-" and added lines with "+".
+// It returns an empty string when they are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	a := strings.SplitAfter(want, "\n")
+	b := strings.SplitAfter(got, "\n")
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	line := func(prefix, s string) {
+		if s == "" {
+			return
+		}
+		buf.WriteString(prefix + strings.TrimSuffix(s, "\n") + "\n")
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			line(" ", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			line("-", a[i])
+			i++
+		default:
+			line("+", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		line("-", a[i])
+	}
+	for ; j < len(b); j++ {
+		line("+", b[j])
+	}
+	return buf.String()
+}
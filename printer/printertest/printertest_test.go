@@ -0,0 +1,34 @@
+package printertest
+
+import (
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestGolden(t *testing.T) {
+	f := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Human").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)))
+
+	Golden(t, "testdata/human.proto", f, false)
+}
+
+func TestDiff(t *testing.T) {
+	table := []struct {
+		want     string
+		got      string
+		expected string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nc\n", "a\nb\nc\n", " a\n+b\n c\n"},
+		{"a\n", "b\n", "-a\n+b\n"},
+	}
+
+	for x, d := range table {
+		if actual := Diff(d.want, d.got); actual != d.expected {
+			t.Errorf("#%d: got\n%s\nwant\n%s", x, actual, d.expected)
+		}
+	}
+}
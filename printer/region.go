@@ -0,0 +1,123 @@
+package printer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// markers of protected regions, followed by the ids of the regions
+const (
+	RegionBegin = "PROTECTED REGION BEGIN"
+	RegionEnd   = "PROTECTED REGION END"
+)
+
+// ProtectedRegion returns the comment marking an empty protected region of the id.
+// Set it to the comment of a declaration to place the region before the declaration.
+func ProtectedRegion(id string) pbast.Comment {
+	return pbast.Comment{RegionBegin + " " + id, RegionEnd + " " + id}
+}
+
+// PreserveRegions returns the post processor keeping the content of the protected
+// regions of the previously written files, e.g. options or RPCs maintained
+// by hand, in place of the content generated in the regions.
+// previous returns the previous content of the file of the name; an error
+// satisfying os.IsNotExist is taken as no previous content.
+// It returns an error when a region of the previous file is missing in
+// the new one not to lose the content.
+func PreserveRegions(previous func(name string) ([]byte, error)) PostProcessor {
+	return PostProcessorFunc(func(name string, data []byte) ([]byte, error) {
+		prev, err := previous(name)
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		preserved := map[string][]string{}
+		if err := scanRegions(prev, func(id string, content []string) {
+			preserved[id] = content
+		}); err != nil {
+			return nil, err
+		}
+
+		buf := new(bytes.Buffer)
+		var skipping string
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			line := s.Text()
+			if skipping != "" {
+				if regionMarker(line, RegionEnd) != skipping {
+					continue
+				}
+				skipping = ""
+			}
+			buf.WriteString(line + "\n")
+
+			id := regionMarker(line, RegionBegin)
+			if content, ok := preserved[id]; ok {
+				for _, l := range content {
+					buf.WriteString(l + "\n")
+				}
+				delete(preserved, id)
+				skipping = id
+			}
+		}
+		if skipping != "" {
+			return nil, fmt.Errorf("protected region %s is not closed", skipping)
+		}
+		if len(preserved) > 0 {
+			var ids []string
+			for id := range preserved {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return nil, fmt.Errorf("protected region %s is not generated anymore", strings.Join(ids, ", "))
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// scanRegions calls fn with the id and the lines of each protected region in the data
+func scanRegions(data []byte, fn func(id string, content []string)) error {
+	var id string
+	var content []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if id == "" {
+			id = regionMarker(line, RegionBegin)
+			content = nil
+			continue
+		}
+		if regionMarker(line, RegionEnd) == id {
+			fn(id, content)
+			id = ""
+			continue
+		}
+		content = append(content, line)
+	}
+	if id != "" {
+		return fmt.Errorf("protected region %s is not closed", id)
+	}
+	return s.Err()
+}
+
+// regionMarker returns the id of the region when the line is a comment of the marker
+func regionMarker(line, marker string) string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "//") {
+		return ""
+	}
+	line = strings.TrimSpace(strings.TrimPrefix(line, "//"))
+	if !strings.HasPrefix(line, marker+" ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, marker))
+}
@@ -0,0 +1,65 @@
+package printer
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestPreserveRegions(t *testing.T) {
+	svc := pbast.NewService("Interfaces").
+		AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("Request"), pbast.NewReturnType("Response")))
+	svc.Comment = ProtectedRegion("options")
+	svc.RPCs[0].Comment = ProtectedRegion("rpcs")
+	f := pbast.NewFile("foo").AddService(svc)
+
+	buf := new(bytes.Buffer)
+	Fprint(buf, f)
+	generated := buf.String()
+
+	edited := bytes.Replace(buf.Bytes(),
+		[]byte("// PROTECTED REGION BEGIN options\n"),
+		[]byte("// PROTECTED REGION BEGIN options\noption java_multiple_files = true;\n"), 1)
+	edited = bytes.Replace(edited,
+		[]byte("  // PROTECTED REGION BEGIN rpcs\n"),
+		[]byte("  // PROTECTED REGION BEGIN rpcs\n  rpc Reset (Request) returns (Response);\n"), 1)
+	if bytes.Count(edited, []byte("PROTECTED REGION")) != 4 || string(edited) == generated {
+		t.Fatalf("unexpected regions in %q", edited)
+	}
+
+	table := []struct {
+		previous []byte
+		err      error
+		expected string
+	}{
+		{edited, nil, string(edited)},
+		{[]byte(generated), nil, generated},
+		{nil, os.ErrNotExist, generated},
+	}
+
+	for x, d := range table {
+		p := PreserveRegions(func(name string) ([]byte, error) {
+			return d.previous, d.err
+		})
+		actual, err := p.Process("foo.proto", []byte(generated))
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+			continue
+		}
+		if string(actual) != d.expected {
+			t.Errorf("#%d: got %q, want %q", x, actual, d.expected)
+		}
+	}
+
+	p := PreserveRegions(func(name string) ([]byte, error) {
+		return edited, nil
+	})
+	svc.Comment = nil
+	buf.Reset()
+	Fprint(buf, f)
+	if _, err := p.Process("foo.proto", buf.Bytes()); err == nil {
+		t.Error("expected an error for the region not generated anymore")
+	}
+}
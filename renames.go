@@ -0,0 +1,52 @@
+package pbast
+
+import "strings"
+
+// RenameTable maps the full names of declarations, without the package,
+// before rewrite passes renamed them onto their current full names
+type RenameTable map[string]string
+
+// RecordRenames composes the renames made by a pass, a map from the full names
+// before the pass onto the ones after, with the renames recorded in the file.
+// Declarations nested in a renamed one follow their parent.
+func (f *File) RecordRenames(renames map[string]string) {
+	if len(renames) == 0 {
+		return
+	}
+
+	origins := map[string]string{}
+	for old, name := range f.Renames {
+		origins[name] = old
+	}
+
+	table := RenameTable{}
+	for old, name := range renames {
+		table[lookupRename(origins, old)] = name
+	}
+	for old, name := range f.Renames {
+		if _, ok := table[old]; !ok {
+			table[old] = lookupRename(renames, name)
+		}
+	}
+	for old, name := range table {
+		if old == name {
+			delete(table, old)
+		}
+	}
+	f.Renames = table
+}
+
+// lookupRename returns the name mapped by renames, or the name of its nearest
+// enclosing scope mapped by renames followed by the rest of the name
+func lookupRename(renames map[string]string, name string) string {
+	for scope := name; ; {
+		if renamed, ok := renames[scope]; ok {
+			return renamed + name[len(scope):]
+		}
+		i := strings.LastIndex(scope, ".")
+		if i < 0 {
+			return name
+		}
+		scope = scope[:i]
+	}
+}
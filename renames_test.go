@@ -0,0 +1,30 @@
+package pbast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecordRenames(t *testing.T) {
+	f := NewFile("org.foo")
+	f.RecordRenames(map[string]string{
+		"interface":       "Interface",
+		"interface.state": "Interface.oper_state",
+		"Vlan":            "Vlan_",
+	})
+	f.RecordRenames(map[string]string{
+		"Interface":            "CiscoInterface",
+		"Interface.oper_state": "CiscoInterface.state",
+		"Interface.Config":     "CiscoInterface.Config_",
+		"Vlan_":                "Vlan",
+	})
+
+	expected := RenameTable{
+		"interface":        "CiscoInterface",
+		"interface.state":  "CiscoInterface.state",
+		"interface.Config": "CiscoInterface.Config_",
+	}
+	if !reflect.DeepEqual(f.Renames, expected) {
+		t.Errorf("got %v, want %v", f.Renames, expected)
+	}
+}
@@ -0,0 +1,49 @@
+package rewrite
+
+import (
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/pbasttest"
+)
+
+// benchmarkModelSize is the number of containers in the benchmarked model
+const benchmarkModelSize = 200
+
+func BenchmarkPasses(b *testing.B) {
+	model := pbasttest.NewModel(benchmarkModelSize)
+	if err := pbast.CheckInvariants(model); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, name := range RegisteredPasses() {
+		p, _ := LookupPass(name)
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				f := model.Clone()
+				b.StartTimer()
+				if _, _, err := p.Run(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	model := pbasttest.NewModel(benchmarkModelSize)
+	p, err := NewPipeline(RegisteredPasses()...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		f := model.Clone()
+		b.StartTimer()
+		if _, _, err := p.Run(f); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
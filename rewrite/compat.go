@@ -0,0 +1,331 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode"
+
+	"github.com/oshothebig/pbast"
+)
+
+// Change is a difference between two revisions of a file
+type Change struct {
+	// Name is the full name of the changed declaration
+	Name    string
+	Message string
+	// Breaking is set when clients of the old revision can't read
+	// the new one in the binary or JSON encoding
+	Breaking bool
+}
+
+func (c Change) String() string {
+	if c.Breaking {
+		return fmt.Sprintf("%s: %s (breaking)", c.Name, c.Message)
+	}
+	return fmt.Sprintf("%s: %s", c.Name, c.Message)
+}
+
+// CompareRevisions reports the changes from the old revision of a file to
+// the new one which matter to clients: removed messages and enums, and fields
+// and enum values removed, renamed, renumbered or retyped. Additions are not
+// reported. The changes are in the order of the declarations in the old file.
+func CompareRevisions(oldFile, newFile *pbast.File) []Change {
+	var changes []Change
+	report := func(name string, breaking bool, format string, args ...interface{}) {
+		changes = append(changes, Change{name, fmt.Sprintf(format, args...), breaking})
+	}
+
+	oldIndex, newIndex := newTypeIndex(oldFile), newTypeIndex(newFile)
+	newMessages := map[string]*pbast.Message{}
+	pbast.WalkMessages(newFile, func(scope string, m *pbast.Message) {
+		newMessages[qualify(scope, m.Name)] = m
+	})
+	pbast.WalkMessages(oldFile, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		n, ok := newMessages[name]
+		if !ok {
+			report(name, true, "message removed")
+			return
+		}
+
+		newFields := messageFields(newFile, newIndex, name, n)
+		oldFields := messageFields(oldFile, oldIndex, name, m)
+		for _, f := range oldFields.list {
+			if nf, ok := newFields.byIndex[f.index]; ok && !oldFields.renumbered(nf.name, f.index) {
+				if nf.name != f.name {
+					report(qualify(name, f.name), true, "field renamed to %s", nf.name)
+				}
+				if nf.typ != f.typ {
+					report(qualify(name, f.name), true, "type changed from %s to %s", f.typ, nf.typ)
+				}
+				continue
+			}
+			if nf, ok := newFields.byName[f.name]; ok {
+				report(qualify(name, f.name), true, "field number changed from %d to %d", f.index, nf.index)
+				continue
+			}
+			if nf, ok := newFields.byIndex[f.index]; ok {
+				report(qualify(name, f.name), true, "field removed and number %d reused by %s", f.index, nf.name)
+			} else if n.IsReserved(f.index) {
+				report(qualify(name, f.name), false, "field removed")
+			} else {
+				report(qualify(name, f.name), false, "field removed without reserving number %d", f.index)
+			}
+		}
+	})
+
+	newEnums := fileEnums(newFile)
+	for _, e := range fileEnums(oldFile).list {
+		n, ok := newEnums.byName[e.name]
+		if !ok {
+			report(e.name, true, "enum removed")
+			continue
+		}
+
+		oldNames := map[string]bool{}
+		for _, v := range e.enum.Fields {
+			oldNames[v.Name] = true
+		}
+		names := map[int]string{}
+		numbers := map[string]int{}
+		for _, v := range n.Fields {
+			names[v.Index] = v.Name
+			numbers[v.Name] = v.Index
+		}
+		for _, v := range e.enum.Fields {
+			if names[v.Index] == v.Name {
+				continue
+			}
+			renamed, reused := names[v.Index]
+			if number, ok := numbers[v.Name]; ok {
+				report(qualify(e.name, v.Name), true, "value number changed from %d to %d", v.Index, number)
+			} else if reused && oldNames[renamed] {
+				report(qualify(e.name, v.Name), true, "value removed and number %d reused by %s", v.Index, renamed)
+			} else if reused {
+				report(qualify(e.name, v.Name), true, "value renamed to %s", renamed)
+			} else if n.IsReserved(v.Index) {
+				report(qualify(e.name, v.Name), true, "value removed")
+			} else {
+				report(qualify(e.name, v.Name), true, "value removed without reserving number %d", v.Index)
+			}
+		}
+	}
+	return changes
+}
+
+// AddCompatibilityShims helps clients of the old revision of a file migrate to
+// the new one. Numbers and names of the fields removed from the messages and
+// of the values removed from the enums are reserved, except the numbers reused
+// by other values, and renamed fields get json_name options holding the JSON
+// names of the old fields unless they already have one.
+func AddCompatibilityShims(oldFile, newFile *pbast.File) {
+	oldIndex, newIndex := newTypeIndex(oldFile), newTypeIndex(newFile)
+	newMessages := map[string]*pbast.Message{}
+	pbast.WalkMessages(newFile, func(scope string, m *pbast.Message) {
+		newMessages[qualify(scope, m.Name)] = m
+	})
+	pbast.WalkMessages(oldFile, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		n, ok := newMessages[name]
+		if !ok {
+			return
+		}
+
+		newFields := messageFields(newFile, newIndex, name, n)
+		oldFields := messageFields(oldFile, oldIndex, name, m)
+		for _, f := range oldFields.list {
+			if nf, ok := newFields.byIndex[f.index]; ok {
+				if nf.name != f.name && !oldFields.renumbered(nf.name, f.index) {
+					alias(nf.node, jsonName(f.node, f.name))
+				}
+				continue
+			}
+			if _, ok := newFields.byName[f.name]; ok {
+				continue
+			}
+			if !n.IsReserved(f.index) {
+				n.AddReservedRange(pbast.NewReservedRange(f.index, f.index))
+			}
+			if !contains(n.ReservedNames, f.name) {
+				n.AddReservedName(f.name)
+			}
+		}
+	})
+
+	newEnums := fileEnums(newFile)
+	for _, e := range fileEnums(oldFile).list {
+		n, ok := newEnums.byName[e.name]
+		if !ok {
+			continue
+		}
+
+		numbers := map[int]bool{}
+		names := map[string]bool{}
+		for _, v := range n.Fields {
+			numbers[v.Index] = true
+			names[v.Name] = true
+		}
+		for _, v := range e.enum.Fields {
+			if names[v.Name] {
+				continue
+			}
+			if !numbers[v.Index] && !n.IsReserved(v.Index) {
+				n.AddReservedRange(pbast.NewReservedRange(v.Index, v.Index))
+			}
+			if !contains(n.ReservedNames, v.Name) {
+				n.AddReservedName(v.Name)
+			}
+		}
+	}
+}
+
+// alias sets json_name of the field to the name unless it is already set
+func alias(field interface{}, name string) {
+	value := strconv.Quote(name)
+	switch field := field.(type) {
+	case *pbast.MessageField:
+		if !hasFieldOption(field.Options, "json_name") {
+			field.AddOption(pbast.NewFieldOption("json_name", value))
+		}
+	case *pbast.MapField:
+		if !hasFieldOption(field.Options, "json_name") {
+			field.AddOption(pbast.NewFieldOption("json_name", value))
+		}
+	case *pbast.OneOfField:
+		if !hasOption(field.Options, "json_name") {
+			field.AddOption(pbast.NewOption("json_name", value))
+		}
+	}
+}
+
+// jsonName returns the name of the field in the JSON encoding: the value of
+// its json_name option if it has one, otherwise the name converted to
+// lowerCamelCase as protoc does
+func jsonName(field interface{}, name string) string {
+	var value string
+	switch field := field.(type) {
+	case *pbast.MessageField:
+		for _, o := range field.Options {
+			if o.Name == "json_name" {
+				value = o.Value
+			}
+		}
+	case *pbast.MapField:
+		for _, o := range field.Options {
+			if o.Name == "json_name" {
+				value = o.Value
+			}
+		}
+	case *pbast.OneOfField:
+		for _, o := range field.Options {
+			if o.Name == "json_name" {
+				value = o.Value
+			}
+		}
+	}
+	if value != "" {
+		if v, err := strconv.Unquote(value); err == nil {
+			return v
+		}
+		return value
+	}
+
+	var b bytes.Buffer
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fieldInfo is a field of a message with its type resolved to a full name
+type fieldInfo struct {
+	name  string
+	index int
+	typ   string
+	node  interface{}
+}
+
+type fieldSet struct {
+	list    []fieldInfo
+	byIndex map[int]fieldInfo
+	byName  map[string]fieldInfo
+}
+
+// renumbered reports whether the field of the name was declared with another number
+func (s fieldSet) renumbered(name string, index int) bool {
+	f, ok := s.byName[name]
+	return ok && f.index != index
+}
+
+// messageFields returns the fields of the message, including those in oneofs,
+// declared with the full name in the file indexed by idx
+func messageFields(f *pbast.File, idx typeIndex, name string, m *pbast.Message) fieldSet {
+	resolve := func(t string) string {
+		if target, ok := idx.resolve(f.Package, name, t); ok {
+			return target
+		}
+		return t
+	}
+
+	s := fieldSet{byIndex: map[int]fieldInfo{}, byName: map[string]fieldInfo{}}
+	add := func(fi fieldInfo) {
+		s.list = append(s.list, fi)
+		s.byIndex[fi.index] = fi
+		s.byName[fi.name] = fi
+	}
+	for _, field := range m.Fields {
+		typ := resolve(field.Type)
+		if field.Repeated {
+			typ = "repeated " + typ
+		}
+		add(fieldInfo{field.Name, field.Index, typ, field})
+	}
+	for _, field := range m.Maps {
+		add(fieldInfo{field.Name, field.Index, fmt.Sprintf("map<%s, %s>", field.KeyType, resolve(field.ValueType)), field})
+	}
+	for _, o := range m.OneOfs {
+		for _, field := range o.Fields {
+			add(fieldInfo{field.Name, field.Index, resolve(field.Type), field})
+		}
+	}
+	return s
+}
+
+type enumInfo struct {
+	name string
+	enum *pbast.Enum
+}
+
+type enumSet struct {
+	list   []enumInfo
+	byName map[string]*pbast.Enum
+}
+
+// fileEnums returns the enums declared in the file including nested ones
+func fileEnums(f *pbast.File) enumSet {
+	s := enumSet{byName: map[string]*pbast.Enum{}}
+	add := func(scope string, e *pbast.Enum) {
+		name := qualify(scope, e.Name)
+		s.list = append(s.list, enumInfo{name, e})
+		s.byName[name] = e
+	}
+	for _, e := range f.Enums {
+		add("", e)
+	}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, e := range m.Enums {
+			add(qualify(scope, m.Name), e)
+		}
+	})
+	return s
+}
@@ -0,0 +1,123 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func compatRevisions() (*pbast.File, *pbast.File) {
+	oldFile := pbast.NewFile("foo").
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+			AddField(pbast.NewMessageField(pbast.UInt32, "mtu", 2)).
+			AddField(pbast.NewMessageField(pbast.String, "description", 3)).
+			AddField(pbast.NewMessageField(pbast.Bool, "enabled", 4)).
+			AddField(pbast.NewMessageField(pbast.NewEnum("Status"), "status", 5)).
+			AddField(pbast.NewMessageField(pbast.String, "alias", 6))).
+		AddMessage(pbast.NewMessage("Counters")).
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0)).
+			AddField(pbast.NewEnumField("DOWN", 1)).
+			AddField(pbast.NewEnumField("TESTING", 2)).
+			AddField(pbast.NewEnumField("DORMANT", 3)).
+			AddField(pbast.NewEnumField("NOT_PRESENT", 4)))
+	newFile := pbast.NewFile("foo").
+		AddMessage(pbast.NewMessage("Interface").
+			AddReservedRange(pbast.NewReservedRange(6, 6)).
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+			AddField(pbast.NewMessageField(pbast.UInt64, "mtu", 2)).
+			AddField(pbast.NewMessageField(pbast.String, "desc", 3)).
+			AddField(pbast.NewMessageField(pbast.Bool, "enabled", 7)).
+			AddField(pbast.NewMessageField(pbast.NewEnum(".foo.Status"), "status", 5))).
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0)).
+			AddField(pbast.NewEnumField("DOWN", 2)).
+			AddField(pbast.NewEnumField("LOWER_LAYER_DOWN", 3)))
+	return oldFile, newFile
+}
+
+func TestCompareRevisions(t *testing.T) {
+	oldFile, newFile := compatRevisions()
+	expected := []Change{
+		{"Interface.mtu", "type changed from uint32 to uint64", true},
+		{"Interface.description", "field renamed to desc", true},
+		{"Interface.enabled", "field number changed from 4 to 7", true},
+		{"Interface.alias", "field removed", false},
+		{"Counters", "message removed", true},
+		{"Status.DOWN", "value number changed from 1 to 2", true},
+		{"Status.TESTING", "value removed and number 2 reused by DOWN", true},
+		{"Status.DORMANT", "value renamed to LOWER_LAYER_DOWN", true},
+		{"Status.NOT_PRESENT", "value removed without reserving number 4", true},
+	}
+
+	actual := CompareRevisions(oldFile, newFile)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %v, want %v", actual, expected)
+	}
+}
+
+func TestAddCompatibilityShims(t *testing.T) {
+	oldFile, newFile := compatRevisions()
+	newFile.Messages[0].Reserved = nil
+	AddCompatibilityShims(oldFile, newFile)
+
+	expected := pbast.NewMessage("Interface").
+		AddReservedRange(pbast.NewReservedRange(6, 6)).
+		AddReservedName("alias").
+		AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+		AddField(pbast.NewMessageField(pbast.UInt64, "mtu", 2)).
+		AddField(pbast.NewMessageField(pbast.String, "desc", 3).
+			AddOption(pbast.NewFieldOption("json_name", `"description"`))).
+		AddField(pbast.NewMessageField(pbast.Bool, "enabled", 7)).
+		AddField(pbast.NewMessageField(pbast.NewEnum(".foo.Status"), "status", 5))
+	if !reflect.DeepEqual(newFile.Messages[0], expected) {
+		t.Errorf("got %v, want %v", newFile.Messages[0], expected)
+	}
+
+	expectedEnum := pbast.NewEnum("Status").
+		AddReservedRange(pbast.NewReservedRange(4, 4)).
+		AddReservedName("TESTING").
+		AddReservedName("DORMANT").
+		AddReservedName("NOT_PRESENT").
+		AddField(pbast.NewEnumField("UP", 0)).
+		AddField(pbast.NewEnumField("DOWN", 2)).
+		AddField(pbast.NewEnumField("LOWER_LAYER_DOWN", 3))
+	if !reflect.DeepEqual(newFile.Enums[0], expectedEnum) {
+		t.Errorf("got %v, want %v", newFile.Enums[0], expectedEnum)
+	}
+
+	changes := CompareRevisions(oldFile, newFile)
+	if c := changes[3]; c.Name != "Interface.alias" || c.Message != "field removed" {
+		t.Errorf("removed field is not reserved: %v", c)
+	}
+	if c := changes[8]; c.Name != "Status.NOT_PRESENT" || c.Message != "value removed" {
+		t.Errorf("removed value is not reserved: %v", c)
+	}
+	if err := pbast.CheckInvariants(newFile); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAddCompatibilityShimsJSONName(t *testing.T) {
+	oldFile := pbast.NewFile("foo").
+		AddMessage(pbast.NewMessage("Person").
+			AddField(pbast.NewMessageField(pbast.String, "first_name", 1)).
+			AddField(pbast.NewMessageField(pbast.String, "last_name", 2).
+				AddOption(pbast.NewFieldOption("json_name", `"surname"`))))
+	newFile := pbast.NewFile("foo").
+		AddMessage(pbast.NewMessage("Person").
+			AddField(pbast.NewMessageField(pbast.String, "given_name", 1)).
+			AddField(pbast.NewMessageField(pbast.String, "family_name", 2)))
+	AddCompatibilityShims(oldFile, newFile)
+
+	expected := pbast.NewMessage("Person").
+		AddField(pbast.NewMessageField(pbast.String, "given_name", 1).
+			AddOption(pbast.NewFieldOption("json_name", `"firstName"`))).
+		AddField(pbast.NewMessageField(pbast.String, "family_name", 2).
+			AddOption(pbast.NewFieldOption("json_name", `"surname"`)))
+	if !reflect.DeepEqual(newFile.Messages[0], expected) {
+		t.Errorf("got %v, want %v", newFile.Messages[0], expected)
+	}
+}
@@ -0,0 +1,48 @@
+package rewrite
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/pbasttest"
+)
+
+// TestConcurrentPasses runs passes concurrently over clones of a shared file.
+// Run it with -race to detect passes touching the shared file.
+func TestConcurrentPasses(t *testing.T) {
+	passes := []func(*pbast.File){
+		func(f *pbast.File) { RenameKeywords(f) },
+		func(f *pbast.File) { AddPrefix(f, "Vendor") },
+		SortMessages,
+		func(f *pbast.File) { InlineMessages(f) },
+		func(f *pbast.File) { LiftMessages(f, LiftOptions{Scope: LiftToCommonAncestor, QualifyDiffering: true}) },
+		func(f *pbast.File) { FindCycles(f) },
+	}
+
+	for _, seed := range fuzzSeeds {
+		shared := pbasttest.NewFile(seed)
+		original := shared.Clone()
+
+		var wg sync.WaitGroup
+		for _, pass := range passes {
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func(pass func(*pbast.File)) {
+					defer wg.Done()
+					f := shared.Clone()
+					pass(f)
+					if err := pbast.CheckInvariants(f); err != nil {
+						t.Error(err)
+					}
+				}(pass)
+			}
+		}
+		wg.Wait()
+
+		if !reflect.DeepEqual(shared, original) {
+			t.Errorf("shared file was modified: got %+v, want %+v", shared, original)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package rewrite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// Cycle is a sequence of messages where each message refers to the next one
+// and the last one refers to the first one
+type Cycle []string
+
+func (c Cycle) String() string {
+	if len(c) == 0 {
+		return ""
+	}
+	names := append([]string{}, c...)
+	return strings.Join(append(names, c[0]), " -> ")
+}
+
+// FindCycles returns cycles of references among messages in the file,
+// one for each set of mutually recursive messages.
+// Recursive messages are valid in protobuf, but passes following
+// references need to take care of them.
+func FindCycles(f *pbast.File) []Cycle {
+	g := newReferenceGraph(f)
+
+	var cycles []Cycle
+	for _, scc := range g.stronglyConnectedComponents() {
+		if c := g.cycleIn(scc); c != nil {
+			cycles = append(cycles, c)
+		}
+	}
+	return cycles
+}
+
+// CheckNesting returns an error when a message is nested in itself
+// directly or indirectly, which can't be represented in a .proto file
+func CheckNesting(f *pbast.File) error {
+	if names := pbast.SelfNestedMessages(f); len(names) > 0 {
+		return fmt.Errorf("message nested in itself: %s", names[0])
+	}
+	return nil
+}
+
+// referenceGraph has messages as vertices and references from fields as edges
+type referenceGraph struct {
+	// names holds the full names of messages in declaration order
+	names []string
+	edges map[string][]string
+}
+
+func newReferenceGraph(f *pbast.File) *referenceGraph {
+	idx := newTypeIndex(f)
+	g := &referenceGraph{
+		edges: map[string][]string{},
+	}
+
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		g.names = append(g.names, name)
+
+		for _, ref := range fieldTypes(m) {
+			target, ok := idx.resolve(f.Package, name, ref)
+			if !ok {
+				continue
+			}
+			if _, ok := idx[target].(*pbast.Message); ok {
+				g.edges[name] = append(g.edges[name], target)
+			}
+		}
+	})
+	return g
+}
+
+// fieldTypes returns the names of the types of the fields in the message
+func fieldTypes(m *pbast.Message) []string {
+	var types []string
+	for _, f := range m.Fields {
+		types = append(types, f.Type)
+	}
+	for _, f := range m.Maps {
+		types = append(types, f.ValueType)
+	}
+	for _, o := range m.OneOfs {
+		for _, f := range o.Fields {
+			types = append(types, f.Type)
+		}
+	}
+	for _, g := range m.Groups {
+		for _, f := range g.Fields {
+			types = append(types, f.Type)
+		}
+	}
+	return types
+}
+
+// stronglyConnectedComponents returns the components by Tarjan's algorithm
+func (g *referenceGraph) stronglyConnectedComponents() [][]string {
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var sccs [][]string
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.edges[v] {
+			if _, ok := index[w]; !ok {
+				connect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+
+		if lowlink[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		sccs = append(sccs, scc)
+	}
+
+	for _, v := range g.names {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+	return sccs
+}
+
+// cycleIn returns a cycle through the messages in the component
+// starting from the one declared first, or nil when there's no cycle
+func (g *referenceGraph) cycleIn(scc []string) Cycle {
+	members := map[string]bool{}
+	for _, v := range scc {
+		members[v] = true
+	}
+
+	var start string
+	for _, v := range g.names {
+		if members[v] {
+			start = v
+			break
+		}
+	}
+
+	// search the shortest path back to the start
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range g.edges[v] {
+			if !members[w] {
+				continue
+			}
+			if w == start {
+				c := Cycle{v}
+				for v != start {
+					v = prev[v]
+					c = append(Cycle{v}, c...)
+				}
+				return c
+			}
+			if _, ok := prev[w]; !ok {
+				prev[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestFindCycles(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		expected []string
+	}{
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1))).
+				AddMessage(pbast.NewMessage("B")),
+			expected: nil,
+		},
+		// self reference
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Tree").
+					AddField(pbast.NewRepeatedMessageField(pbast.NewMessage("Tree"), "children", 1))),
+			expected: []string{"Tree -> Tree"},
+		},
+		// mutual references through a nested message and a map
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1))).
+				AddMessage(pbast.NewMessage("B").
+					AddMessage(pbast.NewMessage("C").
+						AddMapField(pbast.NewMapField(pbast.String, pbast.NewMessage("A"), "a", 1))).
+					AddField(pbast.NewMessageField(pbast.NewMessage("C"), "c", 1))),
+			expected: []string{"A -> B -> B.C -> A"},
+		},
+	}
+
+	for x, d := range table {
+		var actual []string
+		for _, c := range FindCycles(d.in) {
+			actual = append(actual, c.String())
+		}
+		if !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("#%d: got %q, want %q", x, actual, d.expected)
+		}
+	}
+}
+
+func TestCheckNesting(t *testing.T) {
+	m := pbast.NewMessage("A")
+	m.AddMessage(pbast.NewMessage("B").AddMessage(m))
+	f := pbast.NewFile("org.foo").AddMessage(m)
+
+	if err := CheckNesting(f); err == nil {
+		t.Errorf("got no error, want error")
+	}
+
+	// passes terminate even on the cyclic structure
+	RenameKeywords(f)
+	FindCycles(f)
+}
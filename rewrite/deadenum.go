@@ -0,0 +1,85 @@
+package rewrite
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/oshothebig/pbast"
+)
+
+// DeadEnumValues returns the full names of the enum values whose names appear
+// in no option value nor comment in the file, e.g. values of huge enums derived
+// from identities which nothing refers to. Zero values are never reported as
+// they are the defaults. With deprecate, the values are marked deprecated.
+// Values already deprecated are not reported.
+func DeadEnumValues(f *pbast.File, deprecate bool) []string {
+	used := map[string]bool{}
+	collectWords(reflect.ValueOf(f), used, map[uintptr]bool{})
+
+	var dead []string
+	check := func(scope string, e *pbast.Enum) {
+		name := qualify(scope, e.Name)
+		for _, v := range e.Fields {
+			if v.Index == 0 || used[v.Name] || hasEnumValueOption(v.Options, "deprecated") {
+				continue
+			}
+			dead = append(dead, qualify(name, v.Name))
+			if deprecate {
+				v.AddOption(pbast.NewEnumValueOption("deprecated", "true"))
+			}
+		}
+	}
+
+	for _, e := range f.Enums {
+		check("", e)
+	}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, e := range m.Enums {
+			check(qualify(scope, m.Name), e)
+		}
+	})
+	return dead
+}
+
+// collectWords adds the identifiers in the values of options, structs having
+// Name and Value, and in comments to words. Options of enum values are skipped
+// as they annotate the values themselves, e.g. with their original names.
+func collectWords(v reflect.Value, words map[string]bool, visited map[uintptr]bool) {
+	add := func(s string) {
+		for _, w := range strings.FieldsFunc(s, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}) {
+			words[w] = true
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		collectWords(v.Elem(), words, visited)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(pbast.EnumValueOption{}) {
+			return
+		}
+		if name, value := v.FieldByName("Name"), v.FieldByName("Value"); name.IsValid() && value.IsValid() && value.Kind() == reflect.String {
+			add(value.String())
+		}
+		for i := 0; i < v.NumField(); i++ {
+			collectWords(v.Field(i), words, visited)
+		}
+	case reflect.Slice:
+		if c, ok := v.Interface().(pbast.Comment); ok {
+			for _, line := range c {
+				add(line)
+			}
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			collectWords(v.Index(i), words, visited)
+		}
+	}
+}
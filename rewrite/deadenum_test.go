@@ -0,0 +1,48 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestDeadEnumValues(t *testing.T) {
+	input := func() *pbast.File {
+		f := pbast.NewFile("foo").
+			AddOption(pbast.NewOption("(default_type)", "ETHERNET")).
+			AddEnum(pbast.NewEnum("InterfaceType").
+				AddField(pbast.NewEnumField("UNKNOWN", 0)).
+				AddField(pbast.NewEnumField("ETHERNET", 1)).
+				AddField(pbast.NewEnumField("LOOPBACK", 2)).
+				AddField(pbast.NewEnumField("TOKEN_RING", 3).
+					AddOption(pbast.NewEnumValueOption("(yang.enum_name)", `"TOKEN_RING"`))).
+				AddField(pbast.NewEnumField("FDDI", 4).
+					AddOption(pbast.NewEnumValueOption("deprecated", "true")))).
+			AddMessage(pbast.NewMessage("Interface").
+				AddField(pbast.NewMessageField(pbast.NewEnum("InterfaceType"), "type", 1)).
+				AddEnum(pbast.NewEnum("Mode").
+					AddField(pbast.NewEnumField("MODE_UNKNOWN", 0)).
+					AddField(pbast.NewEnumField("MODE_ACCESS", 1))))
+		f.Messages[0].Fields[0].Comment = pbast.Comment{"e.g. LOOPBACK for lo0"}
+		return f
+	}
+
+	f := input()
+	dead := DeadEnumValues(f, false)
+	expectedDead := []string{"InterfaceType.TOKEN_RING", "Interface.Mode.MODE_ACCESS"}
+	if !reflect.DeepEqual(dead, expectedDead) {
+		t.Errorf("got %v, want %v", dead, expectedDead)
+	}
+	if !reflect.DeepEqual(f, input()) {
+		t.Errorf("file is modified without deprecate: %v", f)
+	}
+
+	DeadEnumValues(f, true)
+	expected := input()
+	expected.Enums[0].Fields[3].AddOption(pbast.NewEnumValueOption("deprecated", "true"))
+	expected.Messages[0].Enums[0].Fields[1].AddOption(pbast.NewEnumValueOption("deprecated", "true"))
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("got %v, want %v", f, expected)
+	}
+}
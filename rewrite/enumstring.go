@@ -0,0 +1,55 @@
+package rewrite
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// EnumsAsStrings changes the fields of enum types in the messages selected by
+// the selector to strings, so that consumers accept values added to the enums
+// later without regenerating. The allowed values are documented in the comments
+// of the fields. The enums are kept; RemoveUnreachable removes the unused ones.
+// It returns the full names of the changed fields.
+func EnumsAsStrings(f *pbast.File, s Selector) []string {
+	idx := newTypeIndex(f)
+	names, ms := s.Messages(f)
+
+	var changed []string
+	convert := func(scope, name string, t *string, comment *pbast.Comment) {
+		target, ok := idx.resolve(f.Package, scope, *t)
+		if !ok {
+			return
+		}
+		e, ok := idx[target].(*pbast.Enum)
+		if !ok {
+			return
+		}
+		*t = pbast.String.TypeName()
+		*comment = append(*comment, "Allowed values: "+strings.Join(enumValueNames(e), ", "))
+		changed = append(changed, qualify(scope, name))
+	}
+
+	for i, m := range ms {
+		for _, field := range m.Fields {
+			convert(names[i], field.Name, &field.Type, &field.Comment)
+		}
+		for _, field := range m.Maps {
+			convert(names[i], field.Name, &field.ValueType, &field.Comment)
+		}
+		for _, o := range m.OneOfs {
+			for _, field := range o.Fields {
+				convert(names[i], field.Name, &field.Type, &field.Comment)
+			}
+		}
+	}
+	return changed
+}
+
+func enumValueNames(e *pbast.Enum) []string {
+	names := make([]string, len(e.Fields))
+	for i, v := range e.Fields {
+		names[i] = v.Name
+	}
+	return names
+}
@@ -0,0 +1,50 @@
+package rewrite
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestEnumsAsStrings(t *testing.T) {
+	status := func() *pbast.Enum {
+		return pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0)).
+			AddField(pbast.NewEnumField("DOWN", 1))
+	}
+	documented := func(name string, index int) *pbast.MessageField {
+		f := pbast.NewMessageField(pbast.String, name, index)
+		f.Comment = pbast.Comment{"Allowed values: UP, DOWN"}
+		return f
+	}
+
+	input := pbast.NewFile("foo").
+		AddEnum(status()).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(status(), "oper_status", 1)).
+			AddMapField(pbast.NewMapField(pbast.String, status(), "members", 2)).
+			AddField(pbast.NewMessageField(pbast.String, "name", 3))).
+		AddMessage(pbast.NewMessage("Counters").
+			AddField(pbast.NewMessageField(status(), "status", 1)))
+
+	members := pbast.NewMapField(pbast.String, pbast.String, "members", 2)
+	members.Comment = pbast.Comment{"Allowed values: UP, DOWN"}
+	expected := pbast.NewFile("foo").
+		AddEnum(status()).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(documented("oper_status", 1)).
+			AddMapField(members).
+			AddField(pbast.NewMessageField(pbast.String, "name", 3))).
+		AddMessage(pbast.NewMessage("Counters").
+			AddField(pbast.NewMessageField(status(), "status", 1)))
+
+	changed := EnumsAsStrings(input, Selector{Message: regexp.MustCompile(`^Interface$`)})
+	if expectedChanged := []string{"Interface.oper_status", "Interface.members"}; !reflect.DeepEqual(changed, expectedChanged) {
+		t.Errorf("got %v, want %v", changed, expectedChanged)
+	}
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("got %v, want %v", input, expected)
+	}
+}
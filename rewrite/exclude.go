@@ -0,0 +1,84 @@
+package rewrite
+
+import (
+	"fmt"
+
+	"github.com/oshothebig/pbast"
+)
+
+// ExcludeFields removes the fields of the full names, e.g. "Interface.mtu".
+// With reserve, the numbers and the names of the removed fields are reserved
+// in their messages so that they aren't reused by accident.
+// Oneofs left without fields are removed too.
+// It returns an error without modifying the file when a field is not found.
+func ExcludeFields(f *pbast.File, reserve bool, names ...string) error {
+	excluded := map[string]bool{}
+	for _, name := range names {
+		excluded[name] = true
+	}
+
+	found := map[string]bool{}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, field := range messageFieldNames(m) {
+			if name := qualify(qualify(scope, m.Name), field); excluded[name] {
+				found[name] = true
+			}
+		}
+	})
+	for _, name := range names {
+		if !found[name] {
+			return fmt.Errorf("field %s is not found", name)
+		}
+	}
+
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		exclude := func(field string, index int) bool {
+			if !excluded[qualify(name, field)] {
+				return false
+			}
+			if reserve {
+				if !m.IsReserved(index) {
+					m.AddReservedRange(pbast.NewReservedRange(index, index))
+				}
+				if !contains(m.ReservedNames, field) {
+					m.AddReservedName(field)
+				}
+			}
+			return true
+		}
+
+		var fields []*pbast.MessageField
+		for _, field := range m.Fields {
+			if !exclude(field.Name, field.Index) {
+				fields = append(fields, field)
+			}
+		}
+		m.Fields = fields
+
+		var maps []*pbast.MapField
+		for _, field := range m.Maps {
+			if !exclude(field.Name, field.Index) {
+				maps = append(maps, field)
+			}
+		}
+		m.Maps = maps
+
+		var oneOfs []*pbast.OneOf
+		for _, o := range m.OneOfs {
+			var fields []*pbast.OneOfField
+			for _, field := range o.Fields {
+				if !exclude(field.Name, field.Index) {
+					fields = append(fields, field)
+				}
+			}
+			if len(fields) == 0 && len(o.Fields) > 0 {
+				continue
+			}
+			o.Fields = fields
+			oneOfs = append(oneOfs, o)
+		}
+		m.OneOfs = oneOfs
+	})
+	return nil
+}
@@ -0,0 +1,76 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestExcludeFields(t *testing.T) {
+	input := func() *pbast.File {
+		return pbast.NewFile("foo").
+			AddMessage(pbast.NewMessage("Interface").
+				AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+				AddField(pbast.NewMessageField(pbast.UInt32, "mtu", 2)).
+				AddMapField(pbast.NewMapField(pbast.String, pbast.String, "labels", 3)).
+				AddOneOf(pbast.NewOneOf("address").
+					AddField(pbast.NewOneOfField(pbast.String, "ipv4", 4))))
+	}
+
+	table := []struct {
+		reserve  bool
+		names    []string
+		expected *pbast.File
+		err      bool
+	}{
+		{
+			names: []string{"Interface.mtu", "Interface.ipv4"},
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+					AddMapField(pbast.NewMapField(pbast.String, pbast.String, "labels", 3))),
+		},
+		{
+			reserve: true,
+			names:   []string{"Interface.mtu", "Interface.labels"},
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddReservedRange(pbast.NewReservedRange(2, 2)).
+					AddReservedName("mtu").
+					AddReservedRange(pbast.NewReservedRange(3, 3)).
+					AddReservedName("labels").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+					AddOneOf(pbast.NewOneOf("address").
+						AddField(pbast.NewOneOfField(pbast.String, "ipv4", 4)))),
+		},
+		{
+			names: []string{"Interface.speed"},
+			err:   true,
+		},
+	}
+
+	for x, d := range table {
+		f := input()
+		err := ExcludeFields(f, d.reserve, d.names...)
+		if d.err {
+			if err == nil {
+				t.Errorf("#%d: expected an error", x)
+			}
+			if !reflect.DeepEqual(f, input()) {
+				t.Errorf("#%d: file is modified on error: %v", x, f)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, f, d.expected)
+		}
+		if err := pbast.CheckInvariants(f); err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+		}
+	}
+}
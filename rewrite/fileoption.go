@@ -0,0 +1,35 @@
+package rewrite
+
+import "github.com/oshothebig/pbast"
+
+// SetFileOptions sets the options of the file, e.g. java_multiple_files or
+// custom options such as "(foo.bar)". An option already set is replaced in place
+// and the others are appended in the given order. Values are printed as they are,
+// so string values need to be quoted. Imports needed by custom options are not added.
+func SetFileOptions(f *pbast.File, opts ...*pbast.Option) {
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		o := &pbast.Option{Name: o.Name, Value: o.Value}
+		replaced := false
+		for i, prev := range f.Options {
+			if prev.Name == o.Name {
+				f.Options[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			f.AddOption(o)
+		}
+	}
+}
+
+// FileOptionsPass returns the pass of the name running SetFileOptions
+// with the options over the files selected by the selector
+func FileOptionsPass(name string, s Selector, opts ...*pbast.Option) Pass {
+	return Scoped(NewPass(name, func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		SetFileOptions(f, opts...)
+		return f, nil, nil
+	}), s)
+}
@@ -0,0 +1,45 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestFileOptionsPass(t *testing.T) {
+	p := FileOptionsPass("file-options", Selector{Package: "openconfig.*"},
+		pbast.NewOption("java_multiple_files", "true"),
+		pbast.NewOption("objc_class_prefix", `"OC"`),
+	)
+
+	table := []struct {
+		input    *pbast.File
+		expected *pbast.File
+	}{
+		{
+			input: pbast.NewFile("openconfig.bgp").
+				AddOption(pbast.NewOption("objc_class_prefix", `"BGP"`)).
+				AddOption(pbast.NewOption("go_package", `"bgp"`)),
+			expected: pbast.NewFile("openconfig.bgp").
+				AddOption(pbast.NewOption("objc_class_prefix", `"OC"`)).
+				AddOption(pbast.NewOption("go_package", `"bgp"`)).
+				AddOption(pbast.NewOption("java_multiple_files", "true")),
+		},
+		{
+			input:    pbast.NewFile("ietf.interfaces"),
+			expected: pbast.NewFile("ietf.interfaces"),
+		},
+	}
+
+	for x, d := range table {
+		f, _, err := p.Run(d.input)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, f, d.expected)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+//go:build go1.18
+// +build go1.18
+
+// Fuzz targets need testing.F, which Go 1.18 introduced.
+
+package rewrite
+
+import (
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/pbasttest"
+)
+
+func fuzzPass(f *testing.F, pass func(file *pbast.File)) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file := pbasttest.NewFile(data)
+		checkFuzzInvariants(t, file)
+		pass(file)
+		checkFuzzInvariants(t, file)
+	})
+}
+
+func checkFuzzInvariants(t *testing.T, f *pbast.File) {
+	if err := pbast.CheckInvariants(f); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzRenameKeywords(f *testing.F) {
+	fuzzPass(f, func(file *pbast.File) {
+		RenameKeywords(file)
+	})
+}
+
+func FuzzAddPrefix(f *testing.F) {
+	fuzzPass(f, func(file *pbast.File) {
+		AddPrefix(file, "Vendor")
+	})
+}
+
+func FuzzSortMessages(f *testing.F) {
+	fuzzPass(f, SortMessages)
+}
+
+func FuzzRemoveUnreachable(f *testing.F) {
+	fuzzPass(f, func(file *pbast.File) {
+		RemoveUnreachable(file, file.Messages[0].Name)
+	})
+}
+
+func FuzzInlineMessages(f *testing.F) {
+	fuzzPass(f, func(file *pbast.File) {
+		InlineMessages(file)
+	})
+}
+
+func FuzzLiftMessages(f *testing.F) {
+	fuzzPass(f, func(file *pbast.File) {
+		LiftMessages(file, LiftOptions{Scope: LiftToCommonAncestor, Conflict: RenameConflicts, QualifyDiffering: true})
+	})
+}
@@ -0,0 +1,90 @@
+package rewrite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// GroupPolicy decides how ResolveGroups handles proto2 groups
+type GroupPolicy int
+
+const (
+	// RejectGroups reports groups as an error
+	RejectGroups GroupPolicy = iota
+	// TranslateGroups replaces a group with a nested message and a field of it
+	TranslateGroups
+)
+
+// ResolveGroups removes proto2 groups, which aren't allowed in proto3, from the file.
+// With RejectGroups, the file is left untouched and an error listing the groups is returned.
+// With TranslateGroups, a group is replaced with a nested message of the group name and
+// a field whose name is the lower-cased group name, as protoc does for proto2.
+// When either name is already declared in the message, the file is left untouched
+// and an error listing the colliding names is returned.
+func ResolveGroups(f *pbast.File, policy GroupPolicy) error {
+	var groups []string
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, g := range m.Groups {
+			groups = append(groups, qualify(qualify(scope, m.Name), g.Name))
+		}
+	})
+	if len(groups) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case RejectGroups:
+		return fmt.Errorf("groups are not supported: %s", strings.Join(groups, ", "))
+	case TranslateGroups:
+		var collisions []string
+		pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+			collisions = append(collisions, groupCollisions(qualify(scope, m.Name), m)...)
+		})
+		if len(collisions) > 0 {
+			return fmt.Errorf("groups collide with declared names: %s", strings.Join(collisions, ", "))
+		}
+		pbast.WalkMessages(f, translateGroups)
+		return nil
+	default:
+		return fmt.Errorf("unknown group policy: %d", policy)
+	}
+}
+
+func translateGroups(scope string, m *pbast.Message) {
+	for _, g := range m.Groups {
+		nested := pbast.NewMessage(g.Name)
+		nested.Comment = g.Comment
+		nested.Fields = g.Fields
+		m.AddMessage(nested)
+
+		field := pbast.NewMessageField(nested, strings.ToLower(g.Name), g.Index)
+		field.Repeated = g.Repeated
+		m.AddField(field)
+	}
+	m.Groups = nil
+}
+
+// groupCollisions returns the full names of the messages and fields translated
+// from the groups in the message which collide with other names in the message
+func groupCollisions(name string, m *pbast.Message) []string {
+	withoutGroups := *m
+	withoutGroups.Groups = nil
+	names := scopeNames(&withoutGroups)
+
+	var collisions []string
+	for _, g := range m.Groups {
+		ns := []string{g.Name}
+		if lower := strings.ToLower(g.Name); lower != g.Name {
+			ns = append(ns, lower)
+		}
+		for _, n := range ns {
+			if names[n] {
+				collisions = append(collisions, qualify(name, n))
+			}
+			names[n] = true
+		}
+	}
+	return collisions
+}
@@ -0,0 +1,83 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestResolveGroups(t *testing.T) {
+	newFile := func() *pbast.File {
+		return pbast.NewFile("org.foo").
+			AddMessage(pbast.NewMessage("SearchResponse").
+				AddField(pbast.NewMessageField(pbast.Int32, "total", 1)).
+				AddGroup(pbast.NewRepeatedGroup("Result", 2).
+					AddField(pbast.NewMessageField(pbast.String, "url", 3))))
+	}
+
+	f := newFile()
+	if err := ResolveGroups(f, RejectGroups); err == nil {
+		t.Errorf("got no error, want error")
+	}
+	if !reflect.DeepEqual(f, newFile()) {
+		t.Errorf("got %+v, want untouched file", f)
+	}
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("SearchResponse").
+			AddField(pbast.NewMessageField(pbast.Int32, "total", 1)).
+			AddField(pbast.NewRepeatedMessageField(pbast.NewMessage("Result"), "result", 2)).
+			AddMessage(pbast.NewMessage("Result").
+				AddField(pbast.NewMessageField(pbast.String, "url", 3))))
+
+	if err := ResolveGroups(f, TranslateGroups); err != nil {
+		t.Errorf("got %v, want no error", err)
+	}
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+}
+
+func TestResolveGroupsCollision(t *testing.T) {
+	newFile := func() *pbast.File {
+		return pbast.NewFile("org.foo").
+			AddMessage(pbast.NewMessage("SearchResponse").
+				AddField(pbast.NewMessageField(pbast.Int32, "result", 1)).
+				AddGroup(pbast.NewRepeatedGroup("Result", 2).
+					AddField(pbast.NewMessageField(pbast.String, "url", 3))))
+	}
+
+	f := newFile()
+	err := ResolveGroups(f, TranslateGroups)
+	if err == nil {
+		t.Fatalf("got no error, want error")
+	}
+	if expected := "groups collide with declared names: SearchResponse.result"; err.Error() != expected {
+		t.Errorf("got %q, want %q", err, expected)
+	}
+	if !reflect.DeepEqual(f, newFile()) {
+		t.Errorf("got %+v, want untouched file", f)
+	}
+}
+
+func TestResolveGroupsLowerCase(t *testing.T) {
+	// a lower-case group declares the message and the field of the same name
+	f := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("SearchResponse").
+			AddGroup(pbast.NewGroup("result", 1).
+				AddField(pbast.NewMessageField(pbast.String, "url", 2))))
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("SearchResponse").
+			AddField(pbast.NewMessageField(pbast.NewMessage("result"), "result", 1)).
+			AddMessage(pbast.NewMessage("result").
+				AddField(pbast.NewMessageField(pbast.String, "url", 2))))
+
+	if err := ResolveGroups(f, TranslateGroups); err != nil {
+		t.Errorf("got %v, want no error", err)
+	}
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+}
@@ -0,0 +1,20 @@
+package rewrite
+
+import "github.com/oshothebig/pbast"
+
+// RemoveDuplicateImports removes imports of a file already imported earlier
+// and returns the removed imports
+func RemoveDuplicateImports(f *pbast.File) []*pbast.Import {
+	var kept, removed []*pbast.Import
+	imported := map[string]bool{}
+	for _, i := range f.Imports {
+		if imported[i.Name] {
+			removed = append(removed, i)
+			continue
+		}
+		imported[i.Name] = true
+		kept = append(kept, i)
+	}
+	f.Imports = kept
+	return removed
+}
@@ -0,0 +1,102 @@
+package rewrite
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/oshothebig/pbast"
+)
+
+// InjectFields adds a copy of each field to every message selected by the selector,
+// e.g. operational metadata such as "uint64 last_updated = 1001".
+// Messages already having an identical field are left as they are.
+// It returns the full names of the messages the fields are added to, or an error
+// without modifying the file when a field collides with a declared field in
+// its name or number.
+func InjectFields(f *pbast.File, s Selector, fields ...*pbast.MessageField) ([]string, error) {
+	names, ms := s.Messages(f)
+
+	type injection struct {
+		m     *pbast.Message
+		field *pbast.MessageField
+	}
+	var injections []injection
+	var injected []string
+	for i, m := range ms {
+		added := false
+		for _, field := range fields {
+			ok, err := canInject(m, field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", names[i], err)
+			}
+			if ok {
+				injections = append(injections, injection{m, field})
+				added = true
+			}
+		}
+		if added {
+			injected = append(injected, names[i])
+		}
+	}
+
+	for _, i := range injections {
+		field := *i.field
+		field.Options = append([]*pbast.FieldOption(nil), field.Options...)
+		field.Comment = append(pbast.Comment(nil), field.Comment...)
+		i.m.AddField(&field)
+	}
+	return injected, nil
+}
+
+// canInject reports whether the field needs to be added to the message.
+// It returns an error when the message declares another field of the name or number.
+func canInject(m *pbast.Message, field *pbast.MessageField) (bool, error) {
+	for _, f := range m.Fields {
+		if reflect.DeepEqual(f, field) {
+			return false, nil
+		}
+	}
+
+	collides := func(name string, index int) error {
+		if name == field.Name || index == field.Index {
+			return fmt.Errorf("field %s = %d collides with %s = %d", field.Name, field.Index, name, index)
+		}
+		return nil
+	}
+	for _, f := range m.Fields {
+		if err := collides(f.Name, f.Index); err != nil {
+			return false, err
+		}
+	}
+	for _, f := range m.Maps {
+		if err := collides(f.Name, f.Index); err != nil {
+			return false, err
+		}
+	}
+	for _, o := range m.OneOfs {
+		for _, f := range o.Fields {
+			if err := collides(f.Name, f.Index); err != nil {
+				return false, err
+			}
+		}
+	}
+	for _, g := range m.Groups {
+		if err := collides(g.Name, g.Index); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// InjectFieldsPass returns the pass of the name running InjectFields
+// with the selector and the fields
+func InjectFieldsPass(name string, s Selector, fields ...*pbast.MessageField) Pass {
+	return NewPass(name, func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		injected, err := InjectFields(f, s, fields...)
+		var ds []Diagnostic
+		for _, name := range injected {
+			ds = append(ds, Diagnostic{Message: "fields added to " + name})
+		}
+		return f, ds, err
+	})
+}
@@ -0,0 +1,79 @@
+package rewrite
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestInjectFields(t *testing.T) {
+	lastUpdated := pbast.NewMessageField(pbast.UInt64, "last_updated", 1001)
+
+	table := []struct {
+		input    *pbast.File
+		selector Selector
+		expected *pbast.File
+		injected []string
+	}{
+		{
+			input: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Config").
+					AddMessage(pbast.NewMessage("State"))).
+				AddMessage(pbast.NewMessage("Counters")),
+			selector: Selector{Message: regexp.MustCompile(`State$|^Config$`)},
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Config").
+					AddMessage(pbast.NewMessage("State").
+						AddField(pbast.NewMessageField(pbast.UInt64, "last_updated", 1001))).
+					AddField(pbast.NewMessageField(pbast.UInt64, "last_updated", 1001))).
+				AddMessage(pbast.NewMessage("Counters")),
+			injected: []string{"Config", "Config.State"},
+		},
+		{
+			input: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Config").
+					AddField(pbast.NewMessageField(pbast.UInt64, "last_updated", 1001))),
+			selector: Selector{},
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Config").
+					AddField(pbast.NewMessageField(pbast.UInt64, "last_updated", 1001))),
+		},
+		{
+			input: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Config")),
+			selector: Selector{Package: "bar.*"},
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Config")),
+		},
+	}
+
+	for x, d := range table {
+		injected, err := InjectFields(d.input, d.selector, lastUpdated)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+			continue
+		}
+		if !reflect.DeepEqual(injected, d.injected) {
+			t.Errorf("#%d: got %v, want %v", x, injected, d.injected)
+		}
+		if !reflect.DeepEqual(d.input, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, d.input, d.expected)
+		}
+	}
+}
+
+func TestInjectFieldsCollision(t *testing.T) {
+	f := pbast.NewFile("foo").
+		AddMessage(pbast.NewMessage("Config")).
+		AddMessage(pbast.NewMessage("State").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1001)))
+
+	if _, err := InjectFields(f, Selector{}, pbast.NewMessageField(pbast.UInt64, "last_updated", 1001)); err == nil {
+		t.Fatal("expected an error for the colliding field number")
+	}
+	if len(f.Messages[0].Fields) != 0 {
+		t.Errorf("file is modified on error: %v", f.Messages[0].Fields)
+	}
+}
@@ -0,0 +1,84 @@
+package rewrite
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// InlineMessages moves each top-level message referred from exactly one field
+// into the message declaring the field, the inverse of lifting nested messages.
+// Messages referred from RPCs or extends, or whose name is already used in the
+// destination, are kept at the top level. References to the moved messages
+// are updated accordingly.
+// The returned map holds the full names before inlining as keys
+// and the full names after inlining as values.
+func InlineMessages(f *pbast.File) map[string]string {
+	inlined := map[string]string{}
+	for {
+		refs := resolveReferences(f)
+		i, into := inlineCandidate(f, refs)
+		if i < 0 {
+			f.RecordRenames(inlined)
+			return inlined
+		}
+
+		m := f.Messages[i]
+		idx := newTypeIndex(f)
+		dst := idx[into].(*pbast.Message)
+		f.Messages = append(f.Messages[:i:i], f.Messages[i+1:]...)
+		dst.Messages = append(dst.Messages, m)
+
+		moved := movedTypes(idx, m.Name, qualify(into, m.Name))
+		rebind(f, refs, moved)
+
+		for old, name := range inlined {
+			if n, ok := moved[name]; ok {
+				inlined[old] = n
+			}
+		}
+		inlined[m.Name] = moved[m.Name]
+	}
+}
+
+// inlineCandidate returns the index of the first top-level message which can
+// be inlined and the full name of the message to inline it into.
+// It returns -1 when there is no such message.
+func inlineCandidate(f *pbast.File, refs []resolvedReference) (int, string) {
+	fields := map[*string]bool{}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, field := range m.Fields {
+			fields[&field.Type] = true
+		}
+		for _, field := range m.Maps {
+			fields[&field.ValueType] = true
+		}
+		for _, o := range m.OneOfs {
+			for _, field := range o.Fields {
+				fields[&field.Type] = true
+			}
+		}
+	})
+
+	referrers := map[string][]reference{}
+	for _, r := range refs {
+		referrers[r.target] = append(referrers[r.target], r.reference)
+	}
+
+	idx := newTypeIndex(f)
+	for i, m := range f.Messages {
+		rs := referrers[m.Name]
+		if len(rs) != 1 || !fields[rs[0].name] {
+			continue
+		}
+		into := rs[0].scope
+		if into == m.Name || strings.HasPrefix(into, m.Name+".") {
+			continue
+		}
+		if scopeNames(idx[into].(*pbast.Message))[m.Name] {
+			continue
+		}
+		return i, into
+	}
+	return -1, ""
+}
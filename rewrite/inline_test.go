@@ -0,0 +1,57 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestInlineMessages(t *testing.T) {
+	f := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Shared"), "shared", 2)).
+			AddEnum(pbast.NewEnum("Status"))).
+		AddMessage(pbast.NewMessage("Config").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Counters"), "counters", 1)).
+			AddField(pbast.NewMessageField(pbast.NewEnum("Status"), "status", 2))).
+		AddMessage(pbast.NewMessage("Counters")).
+		AddMessage(pbast.NewMessage("Shared").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Node"), "node", 1))).
+		AddMessage(pbast.NewMessage("Node").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Node"), "next", 1))).
+		AddEnum(pbast.NewEnum("Status")).
+		AddService(pbast.NewService("Interfaces").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("Shared"), pbast.NewReturnType("Interface"))))
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Shared"), "shared", 2)).
+			AddEnum(pbast.NewEnum("Status")).
+			AddMessage(pbast.NewMessage("Config").
+				AddField(pbast.NewMessageField(pbast.NewMessage("Counters"), "counters", 1)).
+				AddField(pbast.NewMessageField(pbast.NewEnum(".org.foo.Status"), "status", 2)).
+				AddMessage(pbast.NewMessage("Counters")))).
+		AddMessage(pbast.NewMessage("Shared").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Node"), "node", 1))).
+		AddMessage(pbast.NewMessage("Node").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Node"), "next", 1))).
+		AddEnum(pbast.NewEnum("Status")).
+		AddService(pbast.NewService("Interfaces").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("Shared"), pbast.NewReturnType("Interface"))))
+
+	inlined := InlineMessages(f)
+	expectedInlined := map[string]string{
+		"Config":   "Interface.Config",
+		"Counters": "Interface.Config.Counters",
+	}
+	if !reflect.DeepEqual(f, withRenames(expected, expectedInlined)) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+
+	if !reflect.DeepEqual(inlined, expectedInlined) {
+		t.Errorf("got %v, want %v", inlined, expectedInlined)
+	}
+}
@@ -0,0 +1,105 @@
+package rewrite
+
+import (
+	"strconv"
+
+	"github.com/oshothebig/pbast"
+)
+
+// AddJSONNames sets the json_name option of the fields renamed by a pass
+// to their names before renaming, so that the JSON encoding of the messages
+// keeps the original names, e.g. hyphenated YANG identifiers.
+// renamed is the map returned by the renaming passes.
+// Fields which already have json_name are left as they are.
+func AddJSONNames(f *pbast.File, renamed map[string]string) {
+	jsonName := originalNames(renamed)
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		for _, field := range m.Fields {
+			if v, ok := jsonName(name, field.Name); ok && !hasFieldOption(field.Options, "json_name") {
+				field.AddOption(pbast.NewFieldOption("json_name", v))
+			}
+		}
+		for _, field := range m.Maps {
+			if v, ok := jsonName(name, field.Name); ok && !hasFieldOption(field.Options, "json_name") {
+				field.AddOption(pbast.NewFieldOption("json_name", v))
+			}
+		}
+		for _, o := range m.OneOfs {
+			for _, field := range o.Fields {
+				if v, ok := jsonName(name, field.Name); ok && !hasOption(field.Options, "json_name") {
+					field.AddOption(pbast.NewOption("json_name", v))
+				}
+			}
+		}
+	})
+}
+
+// AddEnumValueNames sets the option, typically a custom option such as
+// "(yang.enum_name)", of the enum values renamed by a pass to their names
+// before renaming, so that converters can map the values back exactly.
+// renamed is the map returned by the renaming passes.
+// Enum values which already have the option are left as they are.
+func AddEnumValueNames(f *pbast.File, renamed map[string]string, option string) {
+	originalName := originalNames(renamed)
+	annotate := func(scope string, e *pbast.Enum) {
+		name := qualify(scope, e.Name)
+		for _, v := range e.Fields {
+			if old, ok := originalName(name, v.Name); ok && !hasEnumValueOption(v.Options, option) {
+				v.AddOption(pbast.NewEnumValueOption(option, old))
+			}
+		}
+	}
+
+	for _, e := range f.Enums {
+		annotate("", e)
+	}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, e := range m.Enums {
+			annotate(qualify(scope, m.Name), e)
+		}
+	})
+}
+
+// originalNames returns a function looking up the quoted name before renaming
+// of the node declared with the name in the scope
+func originalNames(renamed map[string]string) func(scope, name string) (string, bool) {
+	original := map[string]string{}
+	for old, name := range renamed {
+		original[name] = pbast.BaseName(old)
+	}
+	return func(scope, name string) (string, bool) {
+		old, ok := original[qualify(scope, name)]
+		if !ok || old == name {
+			return "", false
+		}
+		return strconv.Quote(old), true
+	}
+}
+
+func hasEnumValueOption(opts []*pbast.EnumValueOption, name string) bool {
+	for _, o := range opts {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFieldOption(opts []*pbast.FieldOption, name string) bool {
+	for _, o := range opts {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOption(opts []*pbast.Option, name string) bool {
+	for _, o := range opts {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
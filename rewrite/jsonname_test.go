@@ -0,0 +1,70 @@
+package rewrite
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestAddJSONNames(t *testing.T) {
+	f := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Human").
+			AddField(pbast.NewMessageField(pbast.String, "first-name", 1)).
+			AddField(pbast.NewMessageField(pbast.String, "age", 2)).
+			AddMapField(pbast.NewMapField(pbast.String, pbast.String, "last-name", 3).
+				AddOption(pbast.NewFieldOption("json_name", `"ln"`))).
+			AddOneOf(pbast.NewOneOf("id").
+				AddField(pbast.NewOneOfField(pbast.String, "user-id", 4))))
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Human").
+			AddField(pbast.NewMessageField(pbast.String, "first_name", 1).
+				AddOption(pbast.NewFieldOption("json_name", `"first-name"`))).
+			AddField(pbast.NewMessageField(pbast.String, "age", 2)).
+			AddMapField(pbast.NewMapField(pbast.String, pbast.String, "last_name", 3).
+				AddOption(pbast.NewFieldOption("json_name", `"ln"`))).
+			AddOneOf(pbast.NewOneOf("id").
+				AddField(pbast.NewOneOfField(pbast.String, "user_id", 4).
+					AddOption(pbast.NewOption("json_name", `"user-id"`)))))
+
+	renamed := Rename(f, func(name string, n pbast.Node) string {
+		return strings.Replace(pbast.BaseName(name), "-", "_", -1)
+	})
+	AddJSONNames(f, renamed)
+	if !reflect.DeepEqual(f, withRenames(expected, renamed)) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+}
+
+func TestAddEnumValueNames(t *testing.T) {
+	f := pbast.NewFile("org.foo").
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("up", 0)).
+			AddField(pbast.NewEnumField("DOWN", 1))).
+		AddMessage(pbast.NewMessage("Interface").
+			AddEnum(pbast.NewEnum("Mode").
+				AddField(pbast.NewEnumField("full-duplex", 0))))
+
+	expected := pbast.NewFile("org.foo").
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0).
+				AddOption(pbast.NewEnumValueOption("(yang.enum_name)", `"up"`))).
+			AddField(pbast.NewEnumField("DOWN", 1))).
+		AddMessage(pbast.NewMessage("Interface").
+			AddEnum(pbast.NewEnum("Mode").
+				AddField(pbast.NewEnumField("FULL_DUPLEX", 0).
+					AddOption(pbast.NewEnumValueOption("(yang.enum_name)", `"full-duplex"`)))))
+
+	renamed := Rename(f, func(name string, n pbast.Node) string {
+		if _, ok := n.(*pbast.EnumField); ok {
+			return strings.ToUpper(strings.Replace(pbast.BaseName(name), "-", "_", -1))
+		}
+		return pbast.BaseName(name)
+	})
+	AddEnumValueNames(f, renamed, "(yang.enum_name)")
+	if !reflect.DeepEqual(f, withRenames(expected, renamed)) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+}
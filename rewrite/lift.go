@@ -0,0 +1,331 @@
+package rewrite
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// LiftScope decides where LiftMessages moves duplicated nested messages
+type LiftScope int
+
+const (
+	// LiftToFile moves the messages to the top level of the file
+	LiftToFile LiftScope = iota
+	// LiftToCommonAncestor moves the messages to the nearest message
+	// enclosing all of them, or to the top level when there is no such message
+	LiftToCommonAncestor
+)
+
+// LiftConflict decides how LiftMessages handles a lifted message whose name
+// is already used by another declaration in the destination scope
+type LiftConflict int
+
+const (
+	// SkipConflicts leaves the messages nested
+	SkipConflicts LiftConflict = iota
+	// RenameConflicts lifts the message with underscores appended to its name
+	RenameConflicts
+)
+
+// LiftOptions holds the options of LiftMessages
+type LiftOptions struct {
+	Scope    LiftScope
+	Conflict LiftConflict
+	// QualifyDiffering lifts nested messages of the same name which differ
+	// from each other too, prefixing their names with the names of their
+	// enclosing messages, e.g. Interface.Config to InterfaceConfig
+	QualifyDiffering bool
+	// Resolve chooses the name of a message in conflict in place of Conflict
+	// when it is set. A name already declared leaves the message nested.
+	Resolve Resolver
+}
+
+// LiftMessages replaces nested messages declared more than once with the same
+// name and the same structure with a single message declared in the scope.
+// Messages of the same name are left nested when any of them differs from the others
+// unless opts.QualifyDiffering is set.
+// A message already declared in the scope with the same name and structure
+// is reused, and any other declaration of the name is handled by opts.Conflict.
+// References to the lifted messages are updated accordingly.
+// The returned map holds the full names before lifting as keys
+// and the full names after lifting as values.
+func LiftMessages(f *pbast.File, opts LiftOptions) map[string]string {
+	lifted := map[string]string{}
+	for {
+		refs := resolveReferences(f)
+		dst, lifts := liftCandidate(f, refs, opts)
+		if lifts == nil {
+			f.RecordRenames(lifted)
+			return lifted
+		}
+
+		moved := map[string]string{}
+		for _, l := range lifts {
+			for old, name := range liftMessage(f, l.copies, dst, l.name) {
+				moved[old] = name
+			}
+		}
+		rebind(f, refs, moved)
+
+		known := map[string]bool{}
+		for old, name := range lifted {
+			known[name] = true
+			if n, ok := moved[name]; ok {
+				lifted[old] = n
+			}
+		}
+		for _, l := range lifts {
+			for _, c := range l.copies {
+				if !known[c] && moved[c] != c {
+					lifted[c] = moved[c]
+				}
+			}
+		}
+	}
+}
+
+// lift is a set of identical messages to be replaced with a message of the name
+type lift struct {
+	copies []string
+	name   string
+}
+
+// liftMessage moves one of the copies into the scope dst with the name
+// and removes the others. A copy already declared in dst is kept where it is.
+// It returns the full names of the moved types mapped onto the new ones.
+func liftMessage(f *pbast.File, copies []string, dst, name string) map[string]string {
+	idx := newTypeIndex(f)
+	newName := qualify(dst, name)
+
+	moved := map[string]string{}
+	for _, c := range copies {
+		for old, name := range movedTypes(idx, c, newName) {
+			moved[old] = name
+		}
+	}
+
+	keep := idx[copies[0]].(*pbast.Message)
+	inPlace := false
+	for _, c := range copies {
+		m := idx[c].(*pbast.Message)
+		if pbast.ParentName(c) == dst {
+			keep, inPlace = m, true
+			continue
+		}
+		p := idx[pbast.ParentName(c)].(*pbast.Message)
+		p.Messages = removeMessage(p.Messages, m)
+	}
+
+	if !inPlace {
+		keep.Name = name
+		if dst == "" {
+			f.Messages = append(f.Messages, keep)
+		} else {
+			p := idx[dst].(*pbast.Message)
+			p.Messages = append(p.Messages, keep)
+		}
+	}
+	return moved
+}
+
+func removeMessage(ms []*pbast.Message, m *pbast.Message) []*pbast.Message {
+	var kept []*pbast.Message
+	for _, n := range ms {
+		if n != m {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// liftCandidate returns the first messages to lift in declaration order
+// with the scope to lift them to. It returns nil when there are no such messages.
+func liftCandidate(f *pbast.File, refs []resolvedReference, opts LiftOptions) (string, []lift) {
+	var names []string
+	copies := map[string][]string{}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		if scope == "" {
+			return
+		}
+		if _, ok := copies[m.Name]; !ok {
+			names = append(names, m.Name)
+		}
+		copies[m.Name] = append(copies[m.Name], qualify(scope, m.Name))
+	})
+
+	idx := newTypeIndex(f)
+	for _, name := range names {
+		cs := copies[name]
+		if len(cs) < 2 || nested(cs) {
+			continue
+		}
+
+		dst := ""
+		if opts.Scope == LiftToCommonAncestor {
+			dst = commonAncestor(cs)
+		}
+		if declaredIn(cs, dst) {
+			// all copies are in dst already, e.g. a message declared twice
+			continue
+		}
+		declared := declaredNames(f, idx, dst)
+
+		if !sameMessages(idx, refs, cs) {
+			if !opts.QualifyDiffering {
+				continue
+			}
+			var lifts []lift
+			for _, c := range cs {
+				if pbast.ParentName(c) == dst {
+					continue
+				}
+				if name, ok := liftName(declared, dst, pbast.BaseName(pbast.ParentName(c))+name, opts); ok {
+					declared[name] = true
+					lifts = append(lifts, lift{[]string{c}, name})
+				}
+			}
+			if lifts != nil {
+				return dst, lifts
+			}
+			continue
+		}
+
+		if contains(cs, qualify(dst, name)) {
+			return dst, []lift{{cs, name}}
+		}
+		existing := qualify(dst, name)
+		if _, ok := idx[existing].(*pbast.Message); ok && sameMessages(idx, refs, append([]string{existing}, cs...)) {
+			return dst, []lift{{append([]string{existing}, cs...), name}}
+		}
+		if name, ok := liftName(declared, dst, name, opts); ok {
+			return dst, []lift{{cs, name}}
+		}
+	}
+	return "", nil
+}
+
+// liftName returns the name to lift a message to the scope dst where the names
+// are declared, or false when the message can't be lifted
+func liftName(declared map[string]bool, dst, name string, opts LiftOptions) (string, bool) {
+	if !declared[name] {
+		return name, true
+	}
+	if opts.Resolve != nil {
+		names := map[string]bool{}
+		for n := range declared {
+			names[n] = true
+		}
+		resolved, ok := opts.Resolve(NameConflict{Name: qualify(dst, name), Declared: names})
+		if !ok || resolved == "" || declared[resolved] {
+			return "", false
+		}
+		return resolved, true
+	}
+	if opts.Conflict != RenameConflicts {
+		return "", false
+	}
+	for declared[name] {
+		name += "_"
+	}
+	return name, true
+}
+
+// declaredNames returns the names declared in the scope, the top level of
+// the file or a message
+func declaredNames(f *pbast.File, idx typeIndex, scope string) map[string]bool {
+	if scope != "" {
+		return scopeNames(idx[scope].(*pbast.Message))
+	}
+
+	names := map[string]bool{}
+	for _, m := range f.Messages {
+		names[m.Name] = true
+	}
+	for _, e := range f.Enums {
+		names[e.Name] = true
+	}
+	for _, s := range f.Services {
+		names[s.Name] = true
+	}
+	return names
+}
+
+// declaredIn reports whether all of the full names are declared in the scope
+func declaredIn(names []string, scope string) bool {
+	for _, n := range names {
+		if pbast.ParentName(n) != scope {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nested reports whether any of the full names is nested in another
+func nested(names []string) bool {
+	for _, n := range names {
+		for _, m := range names {
+			if strings.HasPrefix(m, n+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameMessages reports whether the messages are structurally identical
+// and their references refer to the same types
+func sameMessages(idx typeIndex, refs []resolvedReference, names []string) bool {
+	first := referenceTargets(refs, names[0])
+	for _, n := range names[1:] {
+		if !pbast.IsSameType(idx[names[0]], idx[n]) {
+			return false
+		}
+		if !reflect.DeepEqual(referenceTargets(refs, n), first) {
+			return false
+		}
+	}
+	return true
+}
+
+// referenceTargets returns the targets of the references in the message.
+// Targets nested in the message are relative to it.
+func referenceTargets(refs []resolvedReference, name string) []string {
+	var targets []string
+	for _, r := range refs {
+		if r.scope != name && !strings.HasPrefix(r.scope, name+".") {
+			continue
+		}
+		target := r.target
+		if target == name || strings.HasPrefix(target, name+".") {
+			target = "." + strings.TrimPrefix(target, name)
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// commonAncestor returns the full name of the nearest message
+// enclosing all of the nested messages
+func commonAncestor(names []string) string {
+	ancestor := strings.Split(pbast.ParentName(names[0]), ".")
+	for _, n := range names[1:] {
+		components := strings.Split(pbast.ParentName(n), ".")
+		i := 0
+		for i < len(ancestor) && i < len(components) && ancestor[i] == components[i] {
+			i++
+		}
+		ancestor = ancestor[:i]
+	}
+	return strings.Join(ancestor, ".")
+}
@@ -0,0 +1,246 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func liftTestConfig() *pbast.Message {
+	return pbast.NewMessage("Config").
+		AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+		AddField(pbast.NewMessageField(pbast.NewEnum("State"), "state", 2)).
+		AddEnum(pbast.NewEnum("State"))
+}
+
+func TestLiftMessages(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		opts     LiftOptions
+		expected *pbast.File
+		lifted   map[string]string
+	}{
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(liftTestConfig()).
+					AddMessage(pbast.NewMessage("Counters").
+						AddField(pbast.NewMessageField(pbast.UInt64, "in", 1)))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Vlan.Config"), "config", 1)).
+					AddField(pbast.NewMessageField(pbast.NewEnum("Config.State"), "state", 2)).
+					AddMessage(liftTestConfig()).
+					AddMessage(pbast.NewMessage("Counters").
+						AddField(pbast.NewMessageField(pbast.UInt64, "out", 1)))),
+			opts: LiftOptions{Scope: LiftToFile},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(pbast.NewMessage("Counters").
+						AddField(pbast.NewMessageField(pbast.UInt64, "in", 1)))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddField(pbast.NewMessageField(pbast.NewEnum("Config.State"), "state", 2)).
+					AddMessage(pbast.NewMessage("Counters").
+						AddField(pbast.NewMessageField(pbast.UInt64, "out", 1)))).
+				AddMessage(liftTestConfig()),
+			lifted: map[string]string{
+				"Interface.Config": "Config",
+				"Vlan.Config":      "Config",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Network").
+					AddMessage(pbast.NewMessage("Site").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+						AddMessage(liftTestConfig())).
+					AddMessage(pbast.NewMessage("Area").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Area.Config"), "config", 1)).
+						AddMessage(liftTestConfig()))),
+			opts: LiftOptions{Scope: LiftToCommonAncestor},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Network").
+					AddMessage(pbast.NewMessage("Site").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1))).
+					AddMessage(pbast.NewMessage("Area").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Network.Config"), "config", 1))).
+					AddMessage(liftTestConfig())),
+			lifted: map[string]string{
+				"Network.Site.Config": "Network.Config",
+				"Network.Area.Config": "Network.Config",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddMessage(pbast.NewMessage("Status").
+						AddField(pbast.NewMessageField(pbast.Bool, "up", 1))).
+					AddMessage(liftTestConfig().
+						AddField(pbast.NewMessageField(pbast.NewMessage("Status"), "other", 3)))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(pbast.NewMessage("Status").
+						AddField(pbast.NewMessageField(pbast.UInt32, "id", 1))).
+					AddMessage(liftTestConfig().
+						AddField(pbast.NewMessageField(pbast.NewMessage("Status"), "other", 3)))),
+			opts: LiftOptions{Scope: LiftToFile},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddMessage(pbast.NewMessage("Status").
+						AddField(pbast.NewMessageField(pbast.Bool, "up", 1))).
+					AddMessage(liftTestConfig().
+						AddField(pbast.NewMessageField(pbast.NewMessage("Status"), "other", 3)))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(pbast.NewMessage("Status").
+						AddField(pbast.NewMessageField(pbast.UInt32, "id", 1))).
+					AddMessage(liftTestConfig().
+						AddField(pbast.NewMessageField(pbast.NewMessage("Status"), "other", 3)))),
+			lifted: map[string]string{},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddMessage(liftTestConfig()).
+					AddMessage(liftTestConfig())),
+			opts: LiftOptions{Scope: LiftToCommonAncestor},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddMessage(liftTestConfig()).
+					AddMessage(liftTestConfig())),
+			lifted: map[string]string{},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Config")),
+			opts: LiftOptions{Scope: LiftToFile, Conflict: SkipConflicts},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Config")),
+			lifted: map[string]string{},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Config")).
+				AddEnum(pbast.NewEnum("Config_")),
+			opts: LiftOptions{Scope: LiftToFile, Conflict: RenameConflicts},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config__"), "config", 1))).
+				AddMessage(pbast.NewMessage("Vlan")).
+				AddMessage(pbast.NewMessage("Config")).
+				AddMessage(func() *pbast.Message {
+					m := liftTestConfig()
+					m.Name = "Config__"
+					return m
+				}()).
+				AddEnum(pbast.NewEnum("Config_")),
+			lifted: map[string]string{
+				"Interface.Config": "Config__",
+				"Vlan.Config":      "Config__",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Config")),
+			opts: LiftOptions{Scope: LiftToFile, Resolve: func(c NameConflict) (string, bool) {
+				if c.Name != "Config" || !c.Declared["Config"] {
+					return "", false
+				}
+				return "SharedConfig", true
+			}},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("SharedConfig"), "config", 1))).
+				AddMessage(pbast.NewMessage("Vlan")).
+				AddMessage(pbast.NewMessage("Config")).
+				AddMessage(func() *pbast.Message {
+					m := liftTestConfig()
+					m.Name = "SharedConfig"
+					return m
+				}()),
+			lifted: map[string]string{
+				"Interface.Config": "SharedConfig",
+				"Vlan.Config":      "SharedConfig",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(liftTestConfig())).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddMessage(liftTestConfig())).
+				AddMessage(liftTestConfig()),
+			opts: LiftOptions{Scope: LiftToFile, Conflict: SkipConflicts},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1))).
+				AddMessage(pbast.NewMessage("Vlan")).
+				AddMessage(liftTestConfig()),
+			lifted: map[string]string{
+				"Interface.Config": "Config",
+				"Vlan.Config":      "Config",
+			},
+		},
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(pbast.NewMessage("Config").
+						AddField(pbast.NewMessageField(pbast.String, "name", 1)))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1)).
+					AddMessage(pbast.NewMessage("Config").
+						AddField(pbast.NewMessageField(pbast.UInt32, "id", 1)))).
+				AddMessage(pbast.NewMessage("Port").
+					AddMessage(pbast.NewMessage("Config"))).
+				AddMessage(pbast.NewMessage("PortConfig")),
+			opts: LiftOptions{Scope: LiftToFile, QualifyDiffering: true},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("InterfaceConfig"), "config", 1))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddField(pbast.NewMessageField(pbast.NewMessage("VlanConfig"), "config", 1))).
+				AddMessage(pbast.NewMessage("Port").
+					AddMessage(pbast.NewMessage("Config"))).
+				AddMessage(pbast.NewMessage("PortConfig")).
+				AddMessage(pbast.NewMessage("InterfaceConfig").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1))).
+				AddMessage(pbast.NewMessage("VlanConfig").
+					AddField(pbast.NewMessageField(pbast.UInt32, "id", 1))),
+			lifted: map[string]string{
+				"Interface.Config": "InterfaceConfig",
+				"Vlan.Config":      "VlanConfig",
+			},
+		},
+	}
+
+	for x, d := range table {
+		lifted := LiftMessages(d.in, d.opts)
+		if !reflect.DeepEqual(d.in, withRenames(d.expected, d.lifted)) {
+			t.Errorf("#%d: got %+v, want %+v", x, d.in, d.expected)
+		}
+		if !reflect.DeepEqual(lifted, d.lifted) {
+			t.Errorf("#%d: got %v, want %v", x, lifted, d.lifted)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package rewrite
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oshothebig/pbast"
+)
+
+// MergePolicy decides how MergeModules handles top-level declarations
+// of the same name in more than one module
+type MergePolicy int
+
+const (
+	// RejectDuplicates reports every duplicated name as a conflict
+	RejectDuplicates MergePolicy = iota
+	// MergeIdentical merges identical declarations into one and
+	// reports the others as conflicts
+	MergeIdentical
+	// PrefixConflicts merges identical declarations into one and
+	// prefixes the others with the names of their modules in PascalCase,
+	// e.g. OpenconfigInterfacesConfig for Config in openconfig-interfaces
+	PrefixConflicts
+)
+
+// Module is a file generated from a module, e.g. a YANG module
+type Module struct {
+	Name string
+	File *pbast.File
+}
+
+// MergeModules combines the files of the modules into a new file with pbast.Merge,
+// applying the policy to the top-level messages, enums and services declared in
+// more than one module. Conflicts are reported as a *pbast.ConflictError.
+// With PrefixConflicts, the files are cloned before renaming so the modules are kept intact.
+func MergeModules(policy MergePolicy, modules ...Module) (*pbast.File, error) {
+	files := make([]*pbast.File, len(modules))
+	for i, m := range modules {
+		files[i] = m.File
+	}
+
+	switch policy {
+	case RejectDuplicates:
+		if names := duplicates(files, false); len(names) > 0 {
+			return nil, &pbast.ConflictError{Names: names}
+		}
+	case PrefixConflicts:
+		files = resolveConflicts(modules, func(c NameConflict) (string, bool) {
+			return modulePrefix(c.Module) + c.Name, true
+		})
+	}
+
+	return pbast.Merge(files...)
+}
+
+// modulePrefix returns the module name in PascalCase without the characters
+// not allowed in protobuf identifiers, which separate the words instead
+func modulePrefix(module string) string {
+	var b bytes.Buffer
+	upper := true
+	for _, r := range module {
+		if r >= utf8.RuneSelf || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	prefix := b.String()
+	if prefix != "" && unicode.IsDigit(rune(prefix[0])) {
+		prefix = "_" + prefix
+	}
+	return prefix
+}
+
+// MergeModulesFunc combines the files of the modules like MergeModules with
+// MergeIdentical, calling resolve with each top-level message, enum and service
+// declared differently in more than one module to choose its name.
+// Declarations left unresolved, or still in conflict after renaming,
+// are reported as a *pbast.ConflictError.
+// The files are cloned before renaming so the modules are kept intact.
+func MergeModulesFunc(resolve Resolver, modules ...Module) (*pbast.File, error) {
+	return pbast.Merge(resolveConflicts(modules, resolve)...)
+}
+
+// resolveConflicts returns the files of the modules with the top-level
+// declarations declared differently among them renamed by resolve
+func resolveConflicts(modules []Module, resolve Resolver) []*pbast.File {
+	files := make([]*pbast.File, len(modules))
+	for i, m := range modules {
+		files[i] = m.File
+	}
+
+	conflicts := map[string]bool{}
+	for _, name := range duplicates(files, true) {
+		conflicts[name] = true
+	}
+	if len(conflicts) == 0 {
+		return files
+	}
+
+	declared := map[string]bool{}
+	for _, f := range files {
+		for _, m := range f.Messages {
+			declared[m.Name] = true
+		}
+		for _, e := range f.Enums {
+			declared[e.Name] = true
+		}
+		for _, s := range f.Services {
+			declared[s.Name] = true
+		}
+	}
+
+	for i, m := range modules {
+		files[i] = m.File.Clone()
+		module := m.Name
+		Rename(files[i], func(name string, n pbast.Node) string {
+			if strings.Contains(name, ".") || !conflicts[name] {
+				return pbast.BaseName(name)
+			}
+			switch n.(type) {
+			case *pbast.Message, *pbast.Enum, *pbast.Service:
+				if resolved, ok := resolve(NameConflict{Name: name, Module: module, Declared: declared}); ok && resolved != "" {
+					return resolved
+				}
+			}
+			return name
+		})
+	}
+	return files
+}
+
+// duplicates returns the top-level names declared in more than one file
+// in the order of appearance. With differing, only the names declared
+// differently are returned.
+func duplicates(files []*pbast.File, differing bool) []string {
+	decls := map[string]interface{}{}
+	found := map[string]bool{}
+	var names []string
+
+	declare := func(name string, decl interface{}) {
+		prev, ok := decls[name]
+		if !ok {
+			decls[name] = decl
+			return
+		}
+		if found[name] || (differing && reflect.DeepEqual(prev, decl)) {
+			return
+		}
+		found[name] = true
+		names = append(names, name)
+	}
+
+	for _, f := range files {
+		for _, m := range f.Messages {
+			declare(m.Name, m)
+		}
+		for _, e := range f.Enums {
+			declare(e.Name, e)
+		}
+		for _, s := range f.Services {
+			declare(s.Name, s)
+		}
+	}
+	return names
+}
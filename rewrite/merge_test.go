@@ -0,0 +1,161 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestMergeModules(t *testing.T) {
+	modules := func() []Module {
+		return []Module{
+			{
+				Name: "openconfig-interfaces",
+				File: pbast.NewFile("org.foo").
+					AddMessage(pbast.NewMessage("Interface").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1))).
+					AddMessage(pbast.NewMessage("Config").
+						AddField(pbast.NewMessageField(pbast.String, "name", 1))).
+					AddEnum(pbast.NewEnum("Status")),
+			},
+			{
+				Name: "openconfig-vlans",
+				File: pbast.NewFile("org.foo").
+					AddMessage(pbast.NewMessage("Vlan").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Config"), "config", 1))).
+					AddMessage(pbast.NewMessage("Config").
+						AddField(pbast.NewMessageField(pbast.UInt32, "id", 1))).
+					AddEnum(pbast.NewEnum("Status")),
+			},
+		}
+	}
+
+	table := []struct {
+		policy    MergePolicy
+		expected  *pbast.File
+		conflicts []string
+		renames   pbast.RenameTable
+	}{
+		{
+			policy:    RejectDuplicates,
+			conflicts: []string{"Config", "Status"},
+		},
+		{
+			policy:    MergeIdentical,
+			conflicts: []string{"Config"},
+		},
+		{
+			policy: PrefixConflicts,
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewMessage("OpenconfigInterfacesConfig"), "config", 1))).
+				AddMessage(pbast.NewMessage("OpenconfigInterfacesConfig").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1))).
+				AddMessage(pbast.NewMessage("Vlan").
+					AddField(pbast.NewMessageField(pbast.NewMessage("OpenconfigVlansConfig"), "config", 1))).
+				AddMessage(pbast.NewMessage("OpenconfigVlansConfig").
+					AddField(pbast.NewMessageField(pbast.UInt32, "id", 1))).
+				AddEnum(pbast.NewEnum("Status")),
+			renames: pbast.RenameTable{"Config": "OpenconfigInterfacesConfig"},
+		},
+	}
+
+	for x, d := range table {
+		in := modules()
+		actual, err := MergeModules(d.policy, in...)
+		if d.conflicts != nil {
+			conflict, ok := err.(*pbast.ConflictError)
+			if !ok {
+				t.Errorf("#%d: got %v, want *pbast.ConflictError", x, err)
+				continue
+			}
+			if !reflect.DeepEqual(conflict.Names, d.conflicts) {
+				t.Errorf("#%d: got %v, want %v", x, conflict.Names, d.conflicts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+			continue
+		}
+		d.expected.Renames = d.renames
+		if !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("#%d: got %+v, want %+v", x, actual, d.expected)
+		}
+		if !reflect.DeepEqual(in, modules()) {
+			t.Errorf("#%d: modules were modified", x)
+		}
+	}
+}
+
+func TestModulePrefix(t *testing.T) {
+	table := []struct {
+		in       string
+		expected string
+	}{
+		{"openconfig-interfaces", "OpenconfigInterfaces"},
+		{"ietf_ip", "IetfIp"},
+		{"OpenConfig.vlan", "OpenConfigVlan"},
+		{"3gpp-common", "_3gppCommon"},
+	}
+
+	for _, d := range table {
+		if actual := modulePrefix(d.in); actual != d.expected {
+			t.Errorf("got %q, want %q", actual, d.expected)
+		}
+	}
+}
+
+func TestMergeModulesFunc(t *testing.T) {
+	modules := []Module{
+		{
+			Name: "interfaces",
+			File: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Config").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1))).
+				AddEnum(pbast.NewEnum("Status")),
+		},
+		{
+			Name: "vlans",
+			File: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Config").
+					AddField(pbast.NewMessageField(pbast.UInt32, "id", 1))).
+				AddEnum(pbast.NewEnum("Status")),
+		},
+	}
+
+	var asked []NameConflict
+	actual, err := MergeModulesFunc(func(c NameConflict) (string, bool) {
+		asked = append(asked, NameConflict{Name: c.Name, Module: c.Module})
+		if c.Module == "interfaces" {
+			return "", false
+		}
+		return "VlanConfig", true
+	}, modules...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Config").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1))).
+		AddEnum(pbast.NewEnum("Status")).
+		AddMessage(pbast.NewMessage("VlanConfig").
+			AddField(pbast.NewMessageField(pbast.UInt32, "id", 1)))
+	expected.Renames = pbast.RenameTable{"Config": "VlanConfig"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %+v, want %+v", actual, expected)
+	}
+
+	expectedAsked := []NameConflict{{Name: "Config", Module: "interfaces"}, {Name: "Config", Module: "vlans"}}
+	if !reflect.DeepEqual(asked, expectedAsked) {
+		t.Errorf("got %v, want %v", asked, expectedAsked)
+	}
+
+	if _, err := MergeModulesFunc(func(c NameConflict) (string, bool) {
+		return "", false
+	}, modules...); err == nil {
+		t.Error("expected an error for the unresolved conflict")
+	}
+}
@@ -0,0 +1,53 @@
+package rewrite
+
+import "github.com/oshothebig/pbast"
+
+// OpenEnums wraps each enum referred from fields in a message declared next
+// to the enum, e.g. StatusValue for Status, holding either a value of the enum
+// or the string of a value unrecognized by the enum, so that values added
+// in newer revisions of the model are tolerated:
+//
+//	message StatusValue {
+//	  oneof kind {
+//	    Status value = 1;
+//	    string unrecognized = 2;
+//	  }
+//	}
+//
+// The fields are changed to refer to the wrappers. Enums whose wrapper name is
+// already declared are left as they are. The returned map holds the full names
+// of the enums as keys and the full names of their wrappers as values.
+func OpenEnums(f *pbast.File) map[string]string {
+	refs := resolveReferences(f)
+	idx := newTypeIndex(f)
+
+	wrapped := map[string]string{}
+	for _, r := range refs {
+		e, ok := idx[r.target].(*pbast.Enum)
+		if !ok {
+			continue
+		}
+		if _, ok := wrapped[r.target]; ok {
+			continue
+		}
+
+		scope := pbast.ParentName(r.target)
+		name := e.Name + "Value"
+		if declaredNames(f, idx, scope)[name] {
+			continue
+		}
+		wrapper := pbast.NewMessage(name).
+			AddOneOf(pbast.NewOneOf("kind").
+				AddField(pbast.NewOneOfField(e, "value", 1)).
+				AddField(pbast.NewOneOfField(pbast.String, "unrecognized", 2)))
+		if scope == "" {
+			f.AddMessage(wrapper)
+		} else {
+			idx[scope].(*pbast.Message).AddMessage(wrapper)
+		}
+		wrapped[r.target] = qualify(scope, name)
+	}
+
+	rebind(f, refs, wrapped)
+	return wrapped
+}
@@ -0,0 +1,73 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestOpenEnums(t *testing.T) {
+	status := pbast.NewEnum("Status").AddField(pbast.NewEnumField("UP", 0))
+	wrapper := func(e *pbast.Enum) *pbast.Message {
+		return pbast.NewMessage(e.Name + "Value").
+			AddOneOf(pbast.NewOneOf("kind").
+				AddField(pbast.NewOneOfField(e, "value", 1)).
+				AddField(pbast.NewOneOfField(pbast.String, "unrecognized", 2)))
+	}
+
+	table := []struct {
+		input    *pbast.File
+		expected *pbast.File
+		wrapped  map[string]string
+	}{
+		{
+			input: pbast.NewFile("foo").
+				AddEnum(status).
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewEnum("Status"), "status", 1)).
+					AddMapField(pbast.NewMapField(pbast.String, pbast.NewEnum("foo.Status"), "members", 2))),
+			expected: pbast.NewFile("foo").
+				AddEnum(status).
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.NewEnum("StatusValue"), "status", 1)).
+					AddMapField(pbast.NewMapField(pbast.String, pbast.NewEnum("foo.StatusValue"), "members", 2))).
+				AddMessage(wrapper(status)),
+			wrapped: map[string]string{"Status": "StatusValue"},
+		},
+		{
+			input: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddEnum(status).
+					AddField(pbast.NewMessageField(status, "status", 1))),
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddEnum(status).
+					AddField(pbast.NewMessageField(pbast.NewEnum("StatusValue"), "status", 1)).
+					AddMessage(wrapper(status))),
+			wrapped: map[string]string{"Interface.Status": "Interface.StatusValue"},
+		},
+		// the wrapper name is taken
+		{
+			input: pbast.NewFile("foo").
+				AddEnum(status).
+				AddMessage(pbast.NewMessage("StatusValue").
+					AddField(pbast.NewMessageField(status, "status", 1))),
+			expected: pbast.NewFile("foo").
+				AddEnum(status).
+				AddMessage(pbast.NewMessage("StatusValue").
+					AddField(pbast.NewMessageField(status, "status", 1))),
+			wrapped: map[string]string{},
+		},
+	}
+
+	for x, d := range table {
+		wrapped := OpenEnums(d.input)
+		if !reflect.DeepEqual(wrapped, d.wrapped) {
+			t.Errorf("#%d: got %v, want %v", x, wrapped, d.wrapped)
+		}
+		if !reflect.DeepEqual(d.input, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, d.input, d.expected)
+		}
+	}
+}
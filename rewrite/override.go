@@ -0,0 +1,124 @@
+package rewrite
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/oshothebig/pbast"
+)
+
+// FieldOverride replaces the type, the name or the number of a field.
+// Zero values keep the generated ones.
+type FieldOverride struct {
+	Type   string
+	Name   string
+	Number int
+}
+
+// OverrideFields applies the overrides keyed by the full names of the fields,
+// e.g. "Interface.mtu", for the cases where the automatic mapping is wrong.
+// The type of a map field overrides its value type. Renamed fields are
+// recorded in the rename table of the file.
+// It returns an error without modifying the file when a field is not found
+// or the overrides make the names or numbers of fields collide.
+func OverrideFields(f *pbast.File, overrides map[string]FieldOverride) error {
+	type target struct {
+		message string
+		name    *string
+		typ     *string
+		index   *int
+	}
+	targets := map[string]target{}
+	messages := map[string]*pbast.Message{}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		messages[name] = m
+		for _, field := range m.Fields {
+			targets[qualify(name, field.Name)] = target{name, &field.Name, &field.Type, &field.Index}
+		}
+		for _, field := range m.Maps {
+			targets[qualify(name, field.Name)] = target{name, &field.Name, &field.ValueType, &field.Index}
+		}
+		for _, o := range m.OneOfs {
+			for _, field := range o.Fields {
+				targets[qualify(name, field.Name)] = target{name, &field.Name, &field.Type, &field.Index}
+			}
+		}
+	})
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		if _, ok := targets[key]; !ok {
+			return fmt.Errorf("field %s is not found", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// check the fields of the messages as overridden
+	checked := map[string]bool{}
+	for _, key := range keys {
+		name := targets[key].message
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+
+		names := map[string]string{}
+		numbers := map[int]string{}
+		for _, field := range messageFieldNames(messages[name]) {
+			t := targets[qualify(name, field)]
+			n, index := *t.name, *t.index
+			if o, ok := overrides[qualify(name, field)]; ok {
+				if o.Name != "" {
+					n = o.Name
+				}
+				if o.Number != 0 {
+					index = o.Number
+				}
+			}
+			if other, ok := names[n]; ok {
+				return fmt.Errorf("%s: name %s of %s collides with %s", name, n, field, other)
+			}
+			if other, ok := numbers[index]; ok {
+				return fmt.Errorf("%s: number %d of %s collides with %s", name, index, field, other)
+			}
+			names[n] = field
+			numbers[index] = field
+		}
+	}
+
+	renamed := map[string]string{}
+	for _, key := range keys {
+		t, o := targets[key], overrides[key]
+		if o.Type != "" {
+			*t.typ = o.Type
+		}
+		if o.Number != 0 {
+			*t.index = o.Number
+		}
+		if o.Name != "" {
+			*t.name = o.Name
+			renamed[key] = qualify(t.message, o.Name)
+		}
+	}
+	f.RecordRenames(renamed)
+	return nil
+}
+
+// messageFieldNames returns the names of the fields in the message including those in oneofs
+func messageFieldNames(m *pbast.Message) []string {
+	var names []string
+	for _, field := range m.Fields {
+		names = append(names, field.Name)
+	}
+	for _, field := range m.Maps {
+		names = append(names, field.Name)
+	}
+	for _, o := range m.OneOfs {
+		for _, field := range o.Fields {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,78 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestOverrideFields(t *testing.T) {
+	input := func() *pbast.File {
+		return pbast.NewFile("foo").
+			AddMessage(pbast.NewMessage("Interface").
+				AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+				AddField(pbast.NewMessageField(pbast.Int64, "mtu", 2)).
+				AddMapField(pbast.NewMapField(pbast.String, pbast.String, "labels", 3)))
+	}
+
+	table := []struct {
+		overrides map[string]FieldOverride
+		expected  *pbast.File
+		err       bool
+	}{
+		{
+			overrides: map[string]FieldOverride{
+				"Interface.mtu":    {Type: "uint32", Name: "max_mtu", Number: 4},
+				"Interface.labels": {Type: "bytes"},
+			},
+			expected: withRenames(pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+					AddField(pbast.NewMessageField(pbast.UInt32, "max_mtu", 4)).
+					AddMapField(pbast.NewMapField(pbast.String, pbast.Bytes, "labels", 3))),
+				map[string]string{"Interface.mtu": "Interface.max_mtu"}),
+		},
+		{
+			overrides: map[string]FieldOverride{"Interface.speed": {Type: "uint64"}},
+			err:       true,
+		},
+		{
+			overrides: map[string]FieldOverride{"Interface.mtu": {Number: 3}},
+			err:       true,
+		},
+		{
+			overrides: map[string]FieldOverride{
+				"Interface.mtu":  {Name: "name"},
+				"Interface.name": {Name: "id"},
+			},
+			expected: withRenames(pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("Interface").
+					AddField(pbast.NewMessageField(pbast.String, "id", 1)).
+					AddField(pbast.NewMessageField(pbast.Int64, "name", 2)).
+					AddMapField(pbast.NewMapField(pbast.String, pbast.String, "labels", 3))),
+				map[string]string{"Interface.mtu": "Interface.name", "Interface.name": "Interface.id"}),
+		},
+	}
+
+	for x, d := range table {
+		f := input()
+		err := OverrideFields(f, d.overrides)
+		if d.err {
+			if err == nil {
+				t.Errorf("#%d: expected an error", x)
+			}
+			if !reflect.DeepEqual(f, input()) {
+				t.Errorf("#%d: file is modified on error: %v", x, f)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", x, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, f, d.expected)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/printer"
+)
+
+// Diagnostic is a message reported by a pass about what it did or found
+type Diagnostic struct {
+	Pass    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s (%s)", d.Message, d.Pass)
+}
+
+// Pass is a rewrite pass which can be looked up by its name.
+// Run returns the rewritten file, which may be the given file modified in place.
+type Pass interface {
+	Name() string
+	Run(f *pbast.File) (*pbast.File, []Diagnostic, error)
+}
+
+type pass struct {
+	name string
+	run  func(f *pbast.File) (*pbast.File, []Diagnostic, error)
+}
+
+// NewPass returns a pass of the name running fn.
+// The diagnostics returned by fn are attributed to the pass.
+func NewPass(name string, fn func(f *pbast.File) (*pbast.File, []Diagnostic, error)) Pass {
+	return &pass{name, fn}
+}
+
+func (p *pass) Name() string {
+	return p.name
+}
+
+func (p *pass) Run(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+	f, ds, err := p.run(f)
+	for i := range ds {
+		ds[i].Pass = p.name
+	}
+	return f, ds, err
+}
+
+// Constrained is implemented by passes which need to run before or after
+// other passes when they are in the same pipeline
+type Constrained interface {
+	Pass
+	// After returns the names of the passes to run before this pass
+	After() []string
+	// Before returns the names of the passes to run after this pass
+	Before() []string
+}
+
+type constrainedPass struct {
+	Pass
+	after  []string
+	before []string
+}
+
+func (p *constrainedPass) After() []string {
+	return p.after
+}
+
+func (p *constrainedPass) Before() []string {
+	return p.before
+}
+
+func constrain(p Pass) *constrainedPass {
+	c, ok := p.(Constrained)
+	if !ok {
+		return &constrainedPass{Pass: p}
+	}
+	if cp, ok := c.(*constrainedPass); ok {
+		p = cp.Pass
+	}
+	return &constrainedPass{
+		Pass:   p,
+		after:  append([]string(nil), c.After()...),
+		before: append([]string(nil), c.Before()...),
+	}
+}
+
+// RunAfter returns the pass which needs to run after the passes of the names
+func RunAfter(p Pass, names ...string) Pass {
+	c := constrain(p)
+	c.after = append(c.after, names...)
+	return c
+}
+
+// RunBefore returns the pass which needs to run before the passes of the names
+func RunBefore(p Pass, names ...string) Pass {
+	c := constrain(p)
+	c.before = append(c.before, names...)
+	return c
+}
+
+var registry = struct {
+	sync.RWMutex
+	passes map[string]Pass
+}{passes: map[string]Pass{}}
+
+// Register makes the pass available by its name.
+// It panics when a pass of the same name is already registered.
+func Register(p Pass) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.passes[p.Name()]; ok {
+		panic("rewrite: pass " + p.Name() + " is already registered")
+	}
+	registry.passes[p.Name()] = p
+}
+
+// LookupPass returns the registered pass of the name
+func LookupPass(name string) (Pass, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	p, ok := registry.passes[name]
+	return p, ok
+}
+
+// RegisteredPasses returns the names of the registered passes in sorted order
+func RegisteredPasses() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.passes))
+	for name := range registry.passes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pipeline runs passes in order
+type Pipeline struct {
+	Passes []Pass
+	// CheckInvariants checks the file with pbast.CheckInvariants after
+	// each pass and stops at the first pass breaking them
+	CheckInvariants bool
+	// Checkpoints receives the file before the first pass and after each pass
+	// in the proto and JSON forms, named by the order and the name of the pass,
+	// e.g. "00-input.proto" and "01-rename-keywords.json", to debug pipelines
+	Checkpoints printer.Output
+}
+
+// NewPipeline returns a pipeline running the registered passes of the names,
+// ordered by Sort to satisfy their constraints
+func NewPipeline(names ...string) (*Pipeline, error) {
+	p := &Pipeline{}
+	for _, name := range names {
+		pass, ok := LookupPass(name)
+		if !ok {
+			return nil, fmt.Errorf("pass %s is not registered", name)
+		}
+		p.Passes = append(p.Passes, pass)
+	}
+	if err := p.Sort(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// orderEdges returns the indices of the passes each pass needs to precede
+func (p *Pipeline) orderEdges() [][]int {
+	indices := map[string][]int{}
+	for i, pass := range p.Passes {
+		indices[pass.Name()] = append(indices[pass.Name()], i)
+	}
+
+	edges := make([][]int, len(p.Passes))
+	for i, pass := range p.Passes {
+		c, ok := pass.(Constrained)
+		if !ok {
+			continue
+		}
+		for _, name := range c.After() {
+			for _, j := range indices[name] {
+				edges[j] = append(edges[j], i)
+			}
+		}
+		for _, name := range c.Before() {
+			edges[i] = append(edges[i], indices[name]...)
+		}
+	}
+	return edges
+}
+
+// Sort reorders the passes to satisfy their constraints, keeping
+// the given order as much as possible. It returns an error when
+// the constraints contradict each other.
+func (p *Pipeline) Sort() error {
+	edges := p.orderEdges()
+	incoming := make([]int, len(p.Passes))
+	for _, es := range edges {
+		for _, j := range es {
+			incoming[j]++
+		}
+	}
+
+	placed := make([]bool, len(p.Passes))
+	sorted := make([]Pass, 0, len(p.Passes))
+	for len(sorted) < len(p.Passes) {
+		next := -1
+		for i := range p.Passes {
+			if !placed[i] && incoming[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var names []string
+			for i, pass := range p.Passes {
+				if !placed[i] {
+					names = append(names, pass.Name())
+				}
+			}
+			return fmt.Errorf("contradicting order constraints among %s", strings.Join(names, ", "))
+		}
+		placed[next] = true
+		sorted = append(sorted, p.Passes[next])
+		for _, j := range edges[next] {
+			incoming[j]--
+		}
+	}
+	p.Passes = sorted
+	return nil
+}
+
+// Validate returns an error when the passes are not in the order
+// their constraints require
+func (p *Pipeline) Validate() error {
+	for i, es := range p.orderEdges() {
+		for _, j := range es {
+			if j <= i {
+				return fmt.Errorf("pass %s needs to run before %s", p.Passes[i].Name(), p.Passes[j].Name())
+			}
+		}
+	}
+	return nil
+}
+
+// Run runs the passes over the file and returns the rewritten file
+// with the diagnostics reported by the passes.
+// Passes out of the order their constraints require are reported
+// as an error before running any pass.
+func (p *Pipeline) Run(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.checkpoint(0, "input", f); err != nil {
+		return nil, nil, err
+	}
+
+	var diags []Diagnostic
+	for i, pass := range p.Passes {
+		var ds []Diagnostic
+		var err error
+		f, ds, err = pass.Run(f)
+		diags = append(diags, ds...)
+		if err != nil {
+			return nil, diags, fmt.Errorf("%s: %v", pass.Name(), err)
+		}
+		if err := p.checkpoint(i+1, pass.Name(), f); err != nil {
+			return nil, diags, err
+		}
+		if p.CheckInvariants {
+			if err := pbast.CheckInvariants(f); err != nil {
+				return nil, diags, fmt.Errorf("%s: %v", pass.Name(), err)
+			}
+		}
+	}
+	return f, diags, nil
+}
+
+func (p *Pipeline) checkpoint(n int, name string, f *pbast.File) error {
+	if p.Checkpoints == nil {
+		return nil
+	}
+
+	base := fmt.Sprintf("%02d-%s", n, name)
+	buf := new(bytes.Buffer)
+	printer.Fprint(buf, f)
+	if err := p.Checkpoints.WriteFile(base+".proto", buf.Bytes()); err != nil {
+		return err
+	}
+
+	b, err := pbast.MarshalJSONFile(f)
+	if err != nil {
+		return err
+	}
+	return p.Checkpoints.WriteFile(base+".json", append(b, '\n'))
+}
+
+func init() {
+	for _, p := range builtinPasses {
+		Register(p)
+	}
+}
+
+// builtinPasses are the passes of this package which take no parameters
+var builtinPasses = []Pass{
+	NewPass("remove-duplicate-imports", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		var ds []Diagnostic
+		for _, i := range RemoveDuplicateImports(f) {
+			ds = append(ds, Diagnostic{Message: i.Name + " removed"})
+		}
+		return f, ds, nil
+	}),
+	NewPass("translate-groups", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, nil, ResolveGroups(f, TranslateGroups)
+	}),
+	NewPass("rename-keywords", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, renameDiagnostics(RenameKeywords(f)), nil
+	}),
+	NewPass("lift-messages", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, renameDiagnostics(LiftMessages(f, LiftOptions{})), nil
+	}),
+	NewPass("inline-messages", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, renameDiagnostics(InlineMessages(f)), nil
+	}),
+	RunAfter(NewPass("sort-messages", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		SortMessages(f)
+		return f, nil, nil
+	}), "translate-groups", "lift-messages", "inline-messages"),
+	NewPass("open-enums", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, renameDiagnostics(OpenEnums(f)), nil
+	}),
+	NewPass("check-nesting", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, nil, CheckNesting(f)
+	}),
+}
+
+// renameDiagnostics reports the renames in the order of the names before renaming
+func renameDiagnostics(renamed map[string]string) []Diagnostic {
+	olds := make([]string, 0, len(renamed))
+	for old := range renamed {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+
+	var ds []Diagnostic
+	for _, old := range olds {
+		ds = append(ds, Diagnostic{Message: old + " -> " + renamed[old]})
+	}
+	return ds
+}
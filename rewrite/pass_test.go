@@ -0,0 +1,171 @@
+package rewrite
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/printer"
+)
+
+func TestPipeline(t *testing.T) {
+	p, err := NewPipeline("remove-duplicate-imports", "rename-keywords", "sort-messages")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.CheckInvariants = true
+
+	f := pbast.NewFile("org.foo").
+		AddImport(pbast.NewImport("a.proto")).
+		AddImport(pbast.NewImport("a.proto")).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("message"), "message", 1))).
+		AddMessage(pbast.NewMessage("message"))
+
+	expected := pbast.NewFile("org.foo").
+		AddImport(pbast.NewImport("a.proto")).
+		AddMessage(pbast.NewMessage("message_")).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("message_"), "message_", 1)))
+	expected.Renames = pbast.RenameTable{
+		"message":           "message_",
+		"Interface.message": "Interface.message_",
+	}
+
+	expectedDiags := []string{
+		"a.proto removed (remove-duplicate-imports)",
+		"Interface.message -> Interface.message_ (rename-keywords)",
+		"message -> message_ (rename-keywords)",
+	}
+
+	actual, ds, err := p.Run(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %+v, want %+v", actual, expected)
+	}
+	var diags []string
+	for _, d := range ds {
+		diags = append(diags, d.String())
+	}
+	if !reflect.DeepEqual(diags, expectedDiags) {
+		t.Errorf("got %q, want %q", diags, expectedDiags)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	if _, err := NewPipeline("rename-keywords", "unknown"); err == nil {
+		t.Errorf("got no error for an unknown pass, want error")
+	}
+
+	broken := NewPass("broken", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		f.AddMessage(pbast.NewMessage("A")).AddMessage(pbast.NewMessage("A"))
+		return f, nil, nil
+	})
+	p := &Pipeline{Passes: []Pass{broken}, CheckInvariants: true}
+	if _, _, err := p.Run(pbast.NewFile("org.foo")); err == nil {
+		t.Errorf("got no error for a pass breaking invariants, want error")
+	}
+
+	failing := NewPass("failing", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return nil, []Diagnostic{{Message: "gave up"}}, errors.New("failed")
+	})
+	p = &Pipeline{Passes: []Pass{failing}}
+	_, ds, err := p.Run(pbast.NewFile("org.foo"))
+	if err == nil || err.Error() != "failing: failed" {
+		t.Errorf("got %v, want failing: failed", err)
+	}
+	if expected := []Diagnostic{{Pass: "failing", Message: "gave up"}}; !reflect.DeepEqual(ds, expected) {
+		t.Errorf("got %v, want %v", ds, expected)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p := NewPass("test-register", func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, nil, nil
+	})
+	// the pass stays registered when the test runs more than once
+	if _, ok := LookupPass(p.Name()); !ok {
+		Register(p)
+	}
+	if actual, ok := LookupPass(p.Name()); !ok || actual.Name() != p.Name() {
+		t.Errorf("got %v, want the registered pass", actual)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("got no panic for a duplicated name, want panic")
+		}
+	}()
+	Register(p)
+}
+
+func TestPipelineOrder(t *testing.T) {
+	p, err := NewPipeline("sort-messages", "rename-keywords", "lift-messages")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, pass := range p.Passes {
+		names = append(names, pass.Name())
+	}
+	if expected := []string{"rename-keywords", "lift-messages", "sort-messages"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("got %v, want %v", names, expected)
+	}
+
+	sortMessages, _ := LookupPass("sort-messages")
+	liftMessages, _ := LookupPass("lift-messages")
+	p = &Pipeline{Passes: []Pass{sortMessages, liftMessages}}
+	if _, _, err := p.Run(pbast.NewFile("org.foo")); err == nil {
+		t.Errorf("got no error for passes out of order, want error")
+	}
+
+	noop := func(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+		return f, nil, nil
+	}
+	p = &Pipeline{Passes: []Pass{
+		RunBefore(NewPass("a", noop), "b"),
+		RunBefore(NewPass("b", noop), "a"),
+	}}
+	if err := p.Sort(); err == nil {
+		t.Errorf("got no error for contradicting constraints, want error")
+	}
+}
+
+func TestPipelineCheckpoints(t *testing.T) {
+	p, err := NewPipeline("rename-keywords", "sort-messages")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := printer.MapOutput{}
+	p.Checkpoints = out
+
+	f := pbast.NewFile("org.foo").AddMessage(pbast.NewMessage("message"))
+	if _, _, err := p.Run(f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for name := range out {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{
+		"00-input.json", "00-input.proto",
+		"01-rename-keywords.json", "01-rename-keywords.proto",
+		"02-sort-messages.json", "02-sort-messages.proto",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("got %v, want %v", names, expected)
+	}
+	if actual := string(out["00-input.proto"]); !strings.Contains(actual, "message message {") {
+		t.Errorf("got %s, want the input file", actual)
+	}
+	if actual := string(out["01-rename-keywords.proto"]); !strings.Contains(actual, "message message_ {") {
+		t.Errorf("got %s, want the renamed file", actual)
+	}
+}
@@ -0,0 +1,25 @@
+package rewrite
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// AddPrefix prefixes the names of top-level messages and enums, e.g. with
+// a vendor or module name, so that types generated from different sources
+// can live in the same package. References to the types are updated accordingly.
+// It returns the map from the full names before renaming to the ones after.
+func AddPrefix(f *pbast.File, prefix string) map[string]string {
+	return Rename(f, func(name string, n pbast.Node) string {
+		if strings.Contains(name, ".") {
+			return pbast.BaseName(name)
+		}
+		switch n.(type) {
+		case *pbast.Message, *pbast.Enum:
+			return prefix + name
+		default:
+			return name
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestAddPrefix(t *testing.T) {
+	f := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Interface.Config"), "config", 1)).
+			AddField(pbast.NewMessageField(pbast.NewEnum("Status"), "status", 2)).
+			AddField(pbast.NewMessageField(pbast.Timestamp, "updated", 3)).
+			AddMessage(pbast.NewMessage("Config"))).
+		AddEnum(pbast.NewEnum("Status")).
+		AddService(pbast.NewService("Interfaces").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("Interface"), pbast.NewReturnType(".org.foo.Interface"))))
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("CiscoInterface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("CiscoInterface.Config"), "config", 1)).
+			AddField(pbast.NewMessageField(pbast.NewEnum("CiscoStatus"), "status", 2)).
+			AddField(pbast.NewMessageField(pbast.Timestamp, "updated", 3)).
+			AddMessage(pbast.NewMessage("Config"))).
+		AddEnum(pbast.NewEnum("CiscoStatus")).
+		AddService(pbast.NewService("Interfaces").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("CiscoInterface"), pbast.NewReturnType(".org.foo.CiscoInterface"))))
+
+	renamed := AddPrefix(f, "Cisco")
+	expectedRenamed := map[string]string{
+		"Interface": "CiscoInterface",
+		"Status":    "CiscoStatus",
+	}
+	if !reflect.DeepEqual(f, withRenames(expected, expectedRenamed)) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+
+	if !reflect.DeepEqual(renamed, expectedRenamed) {
+		t.Errorf("got %v, want %v", renamed, expectedRenamed)
+	}
+}
@@ -0,0 +1,236 @@
+package rewrite
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/oshothebig/pbast"
+)
+
+// EditConflict is a manual edit which ReapplyEdits couldn't carry over
+type EditConflict struct {
+	// Name is the full name of the edited declaration, empty for the file
+	Name    string
+	Message string
+}
+
+func (c EditConflict) String() string {
+	if c.Name == "" {
+		return c.Message
+	}
+	return fmt.Sprintf("%s: %s", c.Name, c.Message)
+}
+
+// ReapplyEdits carries the comments and options edited by hand over to
+// the regenerated file, given the file generated previously as base and
+// the hand-edited version of it. Declarations are matched by their full names.
+// An edit is applied when the generator left the comment or the option as it
+// was in base; otherwise, or when the edited declaration is gone from the
+// regenerated file, it is returned as a conflict and the regenerated one is kept.
+// Declarations added by hand are reported as conflicts too.
+// generated is modified in place.
+func ReapplyEdits(base, edited, generated *pbast.File) []EditConflict {
+	baseNodes := editableNodes(base)
+	generatedNodes := editableNodes(generated)
+
+	var conflicts []EditConflict
+	for _, n := range editableNodes(edited).list {
+		b, ok := baseNodes.byName[n.name]
+		if !ok {
+			conflicts = append(conflicts, EditConflict{n.name, "declared by hand"})
+			continue
+		}
+		g, ok := generatedNodes.byName[n.name]
+		if !ok {
+			if !reflect.DeepEqual(comment(n.node), comment(b)) || !reflect.DeepEqual(options(n.node), options(b)) {
+				conflicts = append(conflicts, EditConflict{n.name, "edited by hand and removed by the generator"})
+			}
+			continue
+		}
+
+		if !mergeComment(b, n.node, g) {
+			conflicts = append(conflicts, EditConflict{n.name, "comment edited by hand and regenerated differently"})
+		}
+		for _, name := range mergeOptions(b, n.node, g) {
+			conflicts = append(conflicts, EditConflict{n.name, "option " + name + " edited by hand and regenerated differently"})
+		}
+	}
+	return conflicts
+}
+
+// mergeComment applies the comment edited from base to generated.
+// It returns false when the comment is changed in both.
+func mergeComment(base, edited, generated reflect.Value) bool {
+	b, e, g := comment(base), comment(edited), comment(generated)
+	switch {
+	case reflect.DeepEqual(e, b), reflect.DeepEqual(g, e):
+		return true
+	case reflect.DeepEqual(g, b):
+		generated.Elem().FieldByName("Comment").Set(reflect.ValueOf(append(pbast.Comment(nil), e...)))
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeOptions applies the options edited from base to generated.
+// It returns the names of the options changed in both.
+func mergeOptions(base, edited, generated reflect.Value) []string {
+	b, e, g := options(base), options(edited), options(generated)
+
+	var names []string
+	seen := map[string]bool{}
+	for _, o := range append(e.list, b.list...) {
+		if seen[o] {
+			continue
+		}
+		seen[o] = true
+		names = append(names, o)
+	}
+
+	var conflicts []string
+	for _, name := range names {
+		bv, bok := b.values[name]
+		ev, eok := e.values[name]
+		gv, gok := g.values[name]
+		switch {
+		case eok == bok && ev == bv, gok == eok && gv == ev:
+		case gok == bok && gv == bv:
+			if eok {
+				setOption(generated, name, ev)
+			} else {
+				removeOption(generated, name)
+			}
+		default:
+			conflicts = append(conflicts, name)
+		}
+	}
+	return conflicts
+}
+
+// editableNode is a declaration holding a comment or options
+type editableNode struct {
+	name string
+	node reflect.Value
+}
+
+type editableNodeSet struct {
+	list   []editableNode
+	byName map[string]reflect.Value
+}
+
+// editableNodes returns the declarations in the file keyed by their full names.
+// Fields and enum values are named after their messages and enums.
+func editableNodes(f *pbast.File) editableNodeSet {
+	s := editableNodeSet{byName: map[string]reflect.Value{}}
+	add := func(name string, n interface{}) {
+		if _, ok := s.byName[name]; ok {
+			return
+		}
+		v := reflect.ValueOf(n)
+		s.list = append(s.list, editableNode{name, v})
+		s.byName[name] = v
+	}
+	addEnum := func(scope string, e *pbast.Enum) {
+		name := qualify(scope, e.Name)
+		add(name, e)
+		for _, v := range e.Fields {
+			add(qualify(name, v.Name), v)
+		}
+	}
+
+	add("", f)
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		add(name, m)
+		for _, field := range m.Fields {
+			add(qualify(name, field.Name), field)
+		}
+		for _, field := range m.Maps {
+			add(qualify(name, field.Name), field)
+		}
+		for _, o := range m.OneOfs {
+			add(qualify(name, o.Name), o)
+			for _, field := range o.Fields {
+				add(qualify(name, field.Name), field)
+			}
+		}
+		for _, e := range m.Enums {
+			addEnum(name, e)
+		}
+	})
+	for _, e := range f.Enums {
+		addEnum("", e)
+	}
+	for _, srv := range f.Services {
+		add(srv.Name, srv)
+		for _, r := range srv.RPCs {
+			add(qualify(srv.Name, r.Name), r)
+		}
+	}
+	return s
+}
+
+// comment returns the comment of the node, nil when the node has none
+func comment(node reflect.Value) pbast.Comment {
+	c := node.Elem().FieldByName("Comment")
+	if !c.IsValid() || c.Len() == 0 {
+		return nil
+	}
+	return c.Interface().(pbast.Comment)
+}
+
+type optionValues struct {
+	list   []string
+	values map[string]string
+}
+
+// options returns the values of the options of the node by their names.
+// Options are the elements of the Options field having Name and Value.
+func options(node reflect.Value) optionValues {
+	s := optionValues{values: map[string]string{}}
+	opts := node.Elem().FieldByName("Options")
+	if !opts.IsValid() {
+		return s
+	}
+	for i := 0; i < opts.Len(); i++ {
+		o := opts.Index(i).Elem()
+		name := o.FieldByName("Name").String()
+		if _, ok := s.values[name]; ok {
+			continue
+		}
+		s.list = append(s.list, name)
+		s.values[name] = o.FieldByName("Value").String()
+	}
+	return s
+}
+
+// setOption sets the option of the node to a new option, as
+// the current one may be shared with other nodes
+func setOption(node reflect.Value, name, value string) {
+	opts := node.Elem().FieldByName("Options")
+	o := reflect.New(opts.Type().Elem().Elem())
+	o.Elem().FieldByName("Name").SetString(name)
+	o.Elem().FieldByName("Value").SetString(value)
+	for i := 0; i < opts.Len(); i++ {
+		if opts.Index(i).Elem().FieldByName("Name").String() == name {
+			opts.Index(i).Set(o)
+			return
+		}
+	}
+	opts.Set(reflect.Append(opts, o))
+}
+
+func removeOption(node reflect.Value, name string) {
+	opts := node.Elem().FieldByName("Options")
+	kept := reflect.MakeSlice(opts.Type(), 0, opts.Len())
+	for i := 0; i < opts.Len(); i++ {
+		if opts.Index(i).Elem().FieldByName("Name").String() != name {
+			kept = reflect.Append(kept, opts.Index(i))
+		}
+	}
+	if kept.Len() == 0 {
+		kept = reflect.Zero(opts.Type())
+	}
+	opts.Set(kept)
+}
@@ -0,0 +1,72 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestReapplyEdits(t *testing.T) {
+	base := pbast.NewFile("foo").
+		AddOption(pbast.NewOption("go_package", `"foo"`)).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+			AddField(pbast.NewMessageField(pbast.UInt32, "mtu", 2)).
+			AddField(pbast.NewMessageField(pbast.String, "alias", 3))).
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0)))
+
+	edited := base.Clone()
+	edited.AddOption(pbast.NewOption("java_multiple_files", "true"))
+	edited.Options[0].Value = `"example.com/foo"`
+	edited.Messages[0].Comment = pbast.Comment{"Interface is a network interface"}
+	edited.Messages[0].Fields[0].AddOption(pbast.NewFieldOption("(validate.rules).string.min_len", "1"))
+	edited.Messages[0].Fields[1].Comment = pbast.Comment{"MTU in bytes"}
+	edited.Messages[0].Fields[2].Comment = pbast.Comment{"alias of the interface"}
+	edited.Messages[0].AddField(pbast.NewMessageField(pbast.String, "note", 4))
+	edited.Enums[0].Fields[0].AddOption(pbast.NewEnumValueOption("(yang.enum_name)", `"up"`))
+
+	generated := pbast.NewFile("foo").
+		AddOption(pbast.NewOption("go_package", `"bar"`)).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1)).
+			AddField(func() *pbast.MessageField {
+				f := pbast.NewMessageField(pbast.UInt64, "mtu", 2)
+				f.Comment = pbast.Comment{"Maximum transmission unit"}
+				return f
+			}())).
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0)))
+
+	expected := pbast.NewFile("foo").
+		AddOption(pbast.NewOption("go_package", `"bar"`)).
+		AddOption(pbast.NewOption("java_multiple_files", "true")).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.String, "name", 1).
+				AddOption(pbast.NewFieldOption("(validate.rules).string.min_len", "1"))).
+			AddField(func() *pbast.MessageField {
+				f := pbast.NewMessageField(pbast.UInt64, "mtu", 2)
+				f.Comment = pbast.Comment{"Maximum transmission unit"}
+				return f
+			}())).
+		AddEnum(pbast.NewEnum("Status").
+			AddField(pbast.NewEnumField("UP", 0).
+				AddOption(pbast.NewEnumValueOption("(yang.enum_name)", `"up"`))))
+	expected.Messages[0].Comment = pbast.Comment{"Interface is a network interface"}
+
+	expectedConflicts := []EditConflict{
+		{"", "option go_package edited by hand and regenerated differently"},
+		{"Interface.mtu", "comment edited by hand and regenerated differently"},
+		{"Interface.alias", "edited by hand and removed by the generator"},
+		{"Interface.note", "declared by hand"},
+	}
+
+	conflicts := ReapplyEdits(base, edited, generated)
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("got %v, want %v", conflicts, expectedConflicts)
+	}
+	if !reflect.DeepEqual(generated, expected) {
+		t.Errorf("got %v, want %v", generated, expected)
+	}
+}
@@ -0,0 +1,249 @@
+package rewrite
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// keywords are the words reserved by the protobuf language
+// and the names of the builtin types
+var keywords = map[string]bool{
+	"syntax":     true,
+	"import":     true,
+	"weak":       true,
+	"public":     true,
+	"package":    true,
+	"option":     true,
+	"optional":   true,
+	"required":   true,
+	"repeated":   true,
+	"oneof":      true,
+	"map":        true,
+	"reserved":   true,
+	"extensions": true,
+	"extend":     true,
+	"group":      true,
+	"to":         true,
+	"max":        true,
+	"enum":       true,
+	"message":    true,
+	"service":    true,
+	"rpc":        true,
+	"stream":     true,
+	"returns":    true,
+	"true":       true,
+	"false":      true,
+	"inf":        true,
+	"nan":        true,
+	"double":     true,
+	"float":      true,
+	"int32":      true,
+	"int64":      true,
+	"uint32":     true,
+	"uint64":     true,
+	"sint32":     true,
+	"sint64":     true,
+	"fixed32":    true,
+	"fixed64":    true,
+	"sfixed32":   true,
+	"sfixed64":   true,
+	"bool":       true,
+	"string":     true,
+	"bytes":      true,
+}
+
+// RenameKeywords renames messages, enums and fields whose names collide with
+// protobuf keywords or with the first component of the package name
+// by appending underscores.
+// References to the renamed types are updated accordingly.
+// The returned map holds the full names before renaming as keys
+// and the full names after renaming as values.
+func RenameKeywords(f *pbast.File) map[string]string {
+	pkg := string(f.Package)
+	collides := func(name string) bool {
+		if keywords[name] {
+			return true
+		}
+		if pkg == "" {
+			return false
+		}
+		// a name equal to the first component of the package shadows the package
+		// in references qualified by it
+		if i := strings.Index(pkg, "."); i >= 0 {
+			return name == pkg[:i]
+		}
+		return name == pkg
+	}
+
+	return Rename(f, func(fullName string, n pbast.Node) string {
+		name := pbast.BaseName(fullName)
+		switch n.(type) {
+		case *pbast.Message, *pbast.Enum, *pbast.MessageField, *pbast.MapField, *pbast.OneOfField:
+			for collides(name) {
+				name += "_"
+			}
+		}
+		return name
+	})
+}
+
+// RenamePackage changes the package of the file to pkg.
+// References qualified by the old package to the types declared in the file
+// are updated so that they still refer to the same types.
+func RenamePackage(f *pbast.File, pkg pbast.Package) {
+	refs := resolveReferences(f)
+	f.Package = pkg
+	rebind(f, refs, nil)
+}
+
+// RenameFunc returns the new name of the node declared with the full name.
+// Returning the current name keeps the node as it is.
+type RenameFunc func(fullName string, n pbast.Node) string
+
+// Rename renames messages, enums, fields, maps, oneofs, groups, enum values, services and RPCs
+// in the file with the names returned by fn.
+// A new name is made unique in its scope by appending underscores, and
+// references to renamed types are updated accordingly.
+// The returned map holds the full names before renaming as keys
+// and the full names after renaming as values.
+func Rename(f *pbast.File, fn RenameFunc) map[string]string {
+	r := &renamer{
+		fn:      fn,
+		refs:    resolveReferences(f),
+		renamed: map[string]string{},
+		moved:   map[string]string{},
+	}
+	r.renameFile(f)
+
+	for _, ref := range r.refs {
+		if name, ok := r.moved[ref.target]; ok {
+			*ref.name = retarget(f.Package, *ref.name, name)
+		}
+	}
+
+	f.RecordRenames(r.renamed)
+	return r.renamed
+}
+
+type renamer struct {
+	fn   RenameFunc
+	refs []resolvedReference
+	// renamed holds the entries renamed by this pass
+	renamed map[string]string
+	// moved holds the types whose full names changed,
+	// including the ones nested in a renamed message
+	moved map[string]string
+}
+
+func (r *renamer) renameFile(f *pbast.File) {
+	scope := map[string]bool{}
+	for _, m := range f.Messages {
+		scope[m.Name] = true
+	}
+	for _, e := range f.Enums {
+		scope[e.Name] = true
+	}
+	for _, s := range f.Services {
+		scope[s.Name] = true
+	}
+
+	for _, e := range f.Enums {
+		r.renameEnum(e, "", "", scope)
+	}
+
+	// olds maps the full names of the messages after renaming onto the ones
+	// before, and inners holds the names declared in each of them
+	olds := map[string]string{}
+	inners := map[string]map[string]bool{"": scope}
+	pbast.WalkMessages(f, func(newScope string, m *pbast.Message) {
+		oldScope := olds[newScope]
+		oldName := qualify(oldScope, m.Name)
+		r.rename(&m.Name, m, oldScope, newScope, inners[newScope])
+		newName := qualify(newScope, m.Name)
+		olds[newName] = oldName
+		inners[newName] = scopeNames(m)
+		r.renameMembers(m, oldName, newName, inners[newName])
+	})
+	for _, s := range f.Services {
+		old := s.Name
+		r.rename(&s.Name, s, "", "", scope)
+
+		rpcs := map[string]bool{}
+		for _, rpc := range s.RPCs {
+			rpcs[rpc.Name] = true
+		}
+		for _, rpc := range s.RPCs {
+			r.rename(&rpc.Name, rpc, old, s.Name, rpcs)
+		}
+	}
+}
+
+// rename renames a node declared in the scope, whose names are held in names.
+// oldScope and newScope are the full names of the scope before and after renaming.
+func (r *renamer) rename(name *string, n pbast.Node, oldScope, newScope string, names map[string]bool) {
+	oldName := qualify(oldScope, *name)
+	if candidate := r.fn(oldName, n); candidate != *name {
+		delete(names, *name)
+		for names[candidate] {
+			candidate += "_"
+		}
+		*name = candidate
+		names[candidate] = true
+		r.renamed[oldName] = qualify(newScope, candidate)
+	}
+
+	switch n.(type) {
+	case *pbast.Message, *pbast.Enum:
+		if newName := qualify(newScope, *name); newName != oldName {
+			r.moved[oldName] = newName
+		}
+	}
+}
+
+func (r *renamer) renameEnum(e *pbast.Enum, oldScope, newScope string, names map[string]bool) {
+	oldName := qualify(oldScope, e.Name)
+	r.rename(&e.Name, e, oldScope, newScope, names)
+	newName := qualify(newScope, e.Name)
+
+	values := map[string]bool{}
+	for _, f := range e.Fields {
+		values[f.Name] = true
+	}
+	for _, f := range e.Fields {
+		r.rename(&f.Name, f, oldName, newName, values)
+	}
+}
+
+// renameMembers renames the fields, oneofs, groups and enums declared in the message.
+// The nested messages are renamed when they are walked.
+func (r *renamer) renameMembers(m *pbast.Message, oldName, newName string, inner map[string]bool) {
+	for _, f := range m.Fields {
+		r.rename(&f.Name, f, oldName, newName, inner)
+	}
+	for _, f := range m.Maps {
+		r.rename(&f.Name, f, oldName, newName, inner)
+	}
+	for _, o := range m.OneOfs {
+		r.rename(&o.Name, o, oldName, newName, inner)
+		for _, f := range o.Fields {
+			r.rename(&f.Name, f, oldName, newName, inner)
+		}
+	}
+	for _, g := range m.Groups {
+		oldGroup := qualify(oldName, g.Name)
+		r.rename(&g.Name, g, oldName, newName, inner)
+		newGroup := qualify(newName, g.Name)
+
+		fields := map[string]bool{}
+		for _, f := range g.Fields {
+			fields[f.Name] = true
+		}
+		for _, f := range g.Fields {
+			r.rename(&f.Name, f, oldGroup, newGroup, fields)
+		}
+	}
+	for _, e := range m.Enums {
+		r.renameEnum(e, oldName, newName, inner)
+	}
+}
@@ -0,0 +1,136 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestRenameKeywords(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		expected *pbast.File
+		renamed  map[string]string
+	}{
+		// nothing to rename
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.String, "name", 1))),
+			renamed: map[string]string{},
+		},
+		// field named with a keyword
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.String, "syntax", 1)).
+					AddField(pbast.NewMessageField(pbast.String, "syntax_", 2))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.String, "syntax__", 1)).
+					AddField(pbast.NewMessageField(pbast.String, "syntax_", 2))),
+			renamed: map[string]string{
+				"Human.syntax": "Human.syntax__",
+			},
+		},
+		// message named same as the first component of the package, and references to it
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("org").
+					AddMessage(pbast.NewMessage("Inner"))).
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.NewMessage("org"), "a", 1)).
+					AddField(pbast.NewMessageField(pbast.NewMessage("org.Inner"), "b", 2)).
+					AddField(pbast.NewMessageField(pbast.NewMessage(".org.foo.org"), "c", 3))).
+				AddService(pbast.NewService("Get").
+					AddRPC(pbast.NewRPC("Foo", pbast.NewReturnType("Human"), pbast.NewReturnType("org")))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("org_").
+					AddMessage(pbast.NewMessage("Inner"))).
+				AddMessage(pbast.NewMessage("Human").
+					AddField(pbast.NewMessageField(pbast.NewMessage("org_"), "a", 1)).
+					AddField(pbast.NewMessageField(pbast.NewMessage("org_.Inner"), "b", 2)).
+					AddField(pbast.NewMessageField(pbast.NewMessage(".org.foo.org_"), "c", 3))).
+				AddService(pbast.NewService("Get").
+					AddRPC(pbast.NewRPC("Foo", pbast.NewReturnType("Human"), pbast.NewReturnType("org_")))),
+			renamed: map[string]string{
+				"org": "org_",
+			},
+		},
+		// other components of the package don't collide
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("foo")),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("foo")),
+			renamed: map[string]string{},
+		},
+		// a package of one component
+		{
+			in: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("foo")),
+			expected: pbast.NewFile("foo").
+				AddMessage(pbast.NewMessage("foo_")),
+			renamed: map[string]string{
+				"foo": "foo_",
+			},
+		},
+		// nested enum named with a keyword
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddEnum(pbast.NewEnum("enum")).
+					AddField(pbast.NewMessageField(pbast.NewEnum("enum"), "kind", 1))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Human").
+					AddEnum(pbast.NewEnum("enum_")).
+					AddField(pbast.NewMessageField(pbast.NewEnum("enum_"), "kind", 1))),
+			renamed: map[string]string{
+				"Human.enum": "Human.enum_",
+			},
+		},
+	}
+
+	for x, d := range table {
+		renamed := RenameKeywords(d.in)
+		if !reflect.DeepEqual(d.in, withRenames(d.expected, d.renamed)) {
+			t.Errorf("#%d: got %+v, want %+v", x, d.in, d.expected)
+		}
+		if !reflect.DeepEqual(renamed, d.renamed) {
+			t.Errorf("#%d: got %v, want %v", x, renamed, d.renamed)
+		}
+	}
+}
+
+func TestRenamePackage(t *testing.T) {
+	f := pbast.NewFile("Org.Foo").
+		AddMessage(pbast.NewMessage("Org").
+			AddField(pbast.NewMessageField(pbast.NewMessage(".Org.Foo.Bar"), "bar", 1)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Org.Foo.Bar"), "other", 2)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Bar"), "relative", 3)).
+			AddField(pbast.NewMessageField(pbast.NewMessage(".Org.Foo.Common"), "common", 4))).
+		AddMessage(pbast.NewMessage("Bar"))
+
+	expected := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("Org").
+			AddField(pbast.NewMessageField(pbast.NewMessage(".org.foo.Bar"), "bar", 1)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Bar"), "other", 2)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("Bar"), "relative", 3)).
+			AddField(pbast.NewMessageField(pbast.NewMessage(".Org.Foo.Common"), "common", 4))).
+		AddMessage(pbast.NewMessage("Bar"))
+
+	RenamePackage(f, "org.foo")
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("got %+v, want %+v", f, expected)
+	}
+}
+
+// withRenames returns the file with the renames recorded as passes do
+func withRenames(f *pbast.File, renames map[string]string) *pbast.File {
+	f.RecordRenames(renames)
+	return f
+}
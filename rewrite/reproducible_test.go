@@ -0,0 +1,83 @@
+package rewrite
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/pbasttest"
+	"github.com/oshothebig/pbast/printer"
+)
+
+// generate runs passes over the file generated from the seed
+// and prints the resulting files in the order of their names
+func generate(seed []byte) []byte {
+	f := pbasttest.NewFile(seed)
+	RenameKeywords(f)
+	LiftMessages(f, LiftOptions{Scope: LiftToCommonAncestor, Conflict: RenameConflicts, QualifyDiffering: true})
+	InlineMessages(f)
+	SortMessages(f)
+	files, err := Split(f, SplitByCount(2, NumberedFileName("gen")))
+	if err != nil {
+		return []byte(err.Error())
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		buf.WriteString("// " + name + "\n")
+		printer.Fprint(buf, files[name])
+	}
+	return buf.Bytes()
+}
+
+func TestReproducible(t *testing.T) {
+	for x, seed := range fuzzSeeds {
+		first := generate(seed)
+		for i := 0; i < 10; i++ {
+			if actual := generate(seed); !bytes.Equal(actual, first) {
+				t.Fatalf("#%d: got\n%s\nwant\n%s", x, actual, first)
+			}
+		}
+	}
+}
+
+// TestLiftOrder checks that the messages lifted out of many scopes at once
+// are declared in the same order on every run
+func TestLiftOrder(t *testing.T) {
+	newFile := func() *pbast.File {
+		f := pbast.NewFile("org.foo")
+		for _, parent := range []string{"Interface", "Vlan", "Bgp", "Acl"} {
+			m := pbast.NewMessage(parent)
+			for _, nested := range []string{"State", "Config", "Counters"} {
+				m.AddMessage(pbast.NewMessage(nested).
+					AddField(pbast.NewMessageField(pbast.String, "name", 1)))
+				m.AddField(pbast.NewMessageField(pbast.NewMessage(nested), strings.ToLower(nested), len(m.Fields)+1))
+			}
+			f.AddMessage(m)
+		}
+		return f
+	}
+
+	print := func() string {
+		f := newFile()
+		LiftMessages(f, LiftOptions{Scope: LiftToCommonAncestor, Conflict: RenameConflicts})
+		buf := new(bytes.Buffer)
+		printer.Fprint(buf, f)
+		return buf.String()
+	}
+
+	first := print()
+	for i := 0; i < 20; i++ {
+		if actual := print(); actual != first {
+			t.Fatalf("got\n%s\nwant\n%s", actual, first)
+		}
+	}
+}
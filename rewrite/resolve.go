@@ -0,0 +1,16 @@
+package rewrite
+
+// NameConflict is a declaration whose name collides with another declaration
+type NameConflict struct {
+	// Name is the full name the declaration has or is about to have
+	Name string
+	// Module is the module declaring it, set by MergeModulesFunc
+	Module string
+	// Declared holds the names already declared in the scope
+	Declared map[string]bool
+}
+
+// Resolver chooses the name, without the scope, of a declaration in conflict,
+// or returns false to leave the conflict unresolved. It lets tools decide
+// interactively or by their own policies.
+type Resolver func(c NameConflict) (string, bool)
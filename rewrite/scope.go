@@ -0,0 +1,233 @@
+package rewrite
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// qualify joins a scope and a name into a dotted full name.
+// Full names used in this package don't include the package name.
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// typeIndex holds all types declared in a file keyed by their full names
+type typeIndex map[string]pbast.Type
+
+func newTypeIndex(f *pbast.File) typeIndex {
+	idx := typeIndex{}
+	for _, e := range f.Enums {
+		idx[e.Name] = e
+	}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		idx[name] = m
+		for _, e := range m.Enums {
+			idx[qualify(name, e.Name)] = e
+		}
+	})
+	return idx
+}
+
+// resolve returns the full name of the type which ref refers to
+// when it is looked up from the scope, following protobuf's scoping rules.
+func (idx typeIndex) resolve(pkg pbast.Package, scope, ref string) (string, bool) {
+	if strings.HasPrefix(ref, ".") {
+		name := trimPackage(pkg, ref[1:])
+		_, ok := idx[name]
+		return name, ok
+	}
+
+	for s := scope; ; s = pbast.ParentName(s) {
+		name := qualify(s, ref)
+		if _, ok := idx[name]; ok {
+			return name, true
+		}
+		if s == "" {
+			break
+		}
+	}
+
+	if name := trimPackage(pkg, ref); name != ref {
+		_, ok := idx[name]
+		return name, ok
+	}
+	return "", false
+}
+
+func trimPackage(pkg pbast.Package, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return strings.TrimPrefix(name, string(pkg)+".")
+}
+
+// reference is a type reference held by a field or an RPC
+type reference struct {
+	scope string
+	name  *string
+}
+
+// references returns all type references in the file
+func references(f *pbast.File) []reference {
+	var refs []reference
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		for _, field := range m.Fields {
+			refs = append(refs, reference{name, &field.Type})
+		}
+		for _, field := range m.Maps {
+			refs = append(refs, reference{name, &field.ValueType})
+		}
+		for _, o := range m.OneOfs {
+			for _, field := range o.Fields {
+				refs = append(refs, reference{name, &field.Type})
+			}
+		}
+		for _, g := range m.Groups {
+			for _, field := range g.Fields {
+				refs = append(refs, reference{name, &field.Type})
+			}
+		}
+		refs = append(refs, extendReferences(name, m.Extends)...)
+	})
+	refs = append(refs, extendReferences("", f.Extends)...)
+	for _, s := range f.Services {
+		for _, r := range s.RPCs {
+			if r.Input != nil {
+				refs = append(refs, reference{"", &r.Input.Name})
+			}
+			if r.Output != nil {
+				refs = append(refs, reference{"", &r.Output.Name})
+			}
+		}
+	}
+	return refs
+}
+
+func extendReferences(scope string, extends []*pbast.Extend) []reference {
+	var refs []reference
+	for _, e := range extends {
+		refs = append(refs, reference{scope, &e.Type})
+		for _, field := range e.Fields {
+			refs = append(refs, reference{scope, &field.Type})
+		}
+	}
+	return refs
+}
+
+// resolvedReference is a reference bound to the full name of its target
+type resolvedReference struct {
+	reference
+	target string
+}
+
+// resolveReferences returns the references in the file which refer to
+// a type declared in the same file
+func resolveReferences(f *pbast.File) []resolvedReference {
+	idx := newTypeIndex(f)
+	var resolved []resolvedReference
+	for _, r := range references(f) {
+		if target, ok := idx.resolve(f.Package, r.scope, *r.name); ok {
+			resolved = append(resolved, resolvedReference{r, target})
+		}
+	}
+	return resolved
+}
+
+// retarget rewrites a reference so that it points to the new full name.
+// The form of the reference, relative or fully qualified, is kept.
+func retarget(pkg pbast.Package, ref, newName string) string {
+	if strings.HasPrefix(ref, ".") {
+		return "." + qualify(string(pkg), newName)
+	}
+
+	// qualified by the package, the rest is already a full name
+	rel := trimPackage(pkg, ref)
+	if rel != ref {
+		return string(pkg) + "." + newName
+	}
+
+	n := strings.Count(rel, ".") + 1
+	components := strings.Split(newName, ".")
+	if n > len(components) {
+		n = len(components)
+	}
+	return strings.Join(components[len(components)-n:], ".")
+}
+
+// rebind rewrites the references so that they refer to the same types after
+// declarations moved. refs are the references resolved before the move and
+// moved maps the full names before the move onto the ones after.
+// A reference which can't be kept relative is fully qualified.
+func rebind(f *pbast.File, refs []resolvedReference, moved map[string]string) {
+	targets := map[*string]string{}
+	for _, r := range refs {
+		targets[r.name] = r.target
+	}
+
+	idx := newTypeIndex(f)
+	for _, r := range references(f) {
+		target, ok := targets[r.name]
+		if !ok {
+			continue
+		}
+		if name, ok := moved[target]; ok {
+			target = name
+		}
+		if name, ok := idx.resolve(f.Package, r.scope, *r.name); ok && name == target {
+			continue
+		}
+
+		ref := retarget(f.Package, *r.name, target)
+		if name, ok := idx.resolve(f.Package, r.scope, ref); !ok || name != target {
+			ref = "." + qualify(string(f.Package), target)
+		}
+		*r.name = ref
+	}
+}
+
+// movedTypes returns the full names of the type and the types nested in it
+// mapped onto the ones after the type is moved from oldName to newName
+func movedTypes(idx typeIndex, oldName, newName string) map[string]string {
+	moved := map[string]string{}
+	for name := range idx {
+		if name == oldName || strings.HasPrefix(name, oldName+".") {
+			moved[name] = newName + strings.TrimPrefix(name, oldName)
+		}
+	}
+	return moved
+}
+
+// scopeNames returns the names declared in the message.
+// A group declares both a nested type and a field of the lower-cased name.
+func scopeNames(m *pbast.Message) map[string]bool {
+	names := map[string]bool{}
+	for _, f := range m.Fields {
+		names[f.Name] = true
+	}
+	for _, f := range m.Maps {
+		names[f.Name] = true
+	}
+	for _, o := range m.OneOfs {
+		names[o.Name] = true
+		for _, f := range o.Fields {
+			names[f.Name] = true
+		}
+	}
+	for _, g := range m.Groups {
+		names[g.Name] = true
+		names[strings.ToLower(g.Name)] = true
+	}
+	for _, n := range m.Messages {
+		names[n.Name] = true
+	}
+	for _, e := range m.Enums {
+		names[e.Name] = true
+	}
+	return names
+}
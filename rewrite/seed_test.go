@@ -0,0 +1,10 @@
+package rewrite
+
+// fuzzSeeds are the seeds of pbasttest.NewFile shared by the fuzz targets
+// and the tests running passes over generated files
+var fuzzSeeds = [][]byte{
+	{},
+	{3, 2, 1, 0, 5, 4, 3, 2, 1, 7, 8, 9},
+	[]byte("the quick brown fox jumps over the lazy dog"),
+	{255, 254, 253, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+}
@@ -0,0 +1,89 @@
+package rewrite
+
+import (
+	"errors"
+	"path"
+	"regexp"
+
+	"github.com/oshothebig/pbast"
+)
+
+// Selector selects files by their packages and messages by their full names
+type Selector struct {
+	// Package is a pattern of path.Match for packages, e.g. "openconfig.bgp.*".
+	// An empty pattern matches any package.
+	Package string
+	// Message matches the full names of messages, without the package.
+	// nil matches any message.
+	Message *regexp.Regexp
+}
+
+// MatchFile reports whether the package of the file matches the selector
+func (s Selector) MatchFile(f *pbast.File) bool {
+	if s.Package == "" {
+		return true
+	}
+	ok, err := path.Match(s.Package, string(f.Package))
+	return err == nil && ok
+}
+
+// MatchMessage reports whether the full name of a message matches the selector
+func (s Selector) MatchMessage(fullName string) bool {
+	return s.Message == nil || s.Message.MatchString(fullName)
+}
+
+// Messages returns the messages in the file selected by the selector
+// with their full names, including nested ones, in declaration order
+func (s Selector) Messages(f *pbast.File) ([]string, []*pbast.Message) {
+	if !s.MatchFile(f) {
+		return nil, nil
+	}
+
+	var names []string
+	var ms []*pbast.Message
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		if s.MatchMessage(name) {
+			names = append(names, name)
+			ms = append(ms, m)
+		}
+	})
+	return names, ms
+}
+
+type scopedPass struct {
+	Pass
+	selector Selector
+}
+
+// Scoped returns the pass which runs p only over the files selected by s.
+// The other files are returned as they are.
+// A pass runs over a whole file, so the pass returns an error
+// when s selects messages as well.
+func Scoped(p Pass, s Selector) Pass {
+	return &scopedPass{p, s}
+}
+
+func (p *scopedPass) Run(f *pbast.File) (*pbast.File, []Diagnostic, error) {
+	if p.selector.Message != nil {
+		return nil, nil, errors.New("scoped pass can't select messages")
+	}
+	if !p.selector.MatchFile(f) {
+		return f, nil, nil
+	}
+	return p.Pass.Run(f)
+}
+
+func (p *scopedPass) After() []string {
+	if c, ok := p.Pass.(Constrained); ok {
+		return c.After()
+	}
+	return nil
+}
+
+func (p *scopedPass) Before() []string {
+	if c, ok := p.Pass.(Constrained); ok {
+		return c.Before()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package rewrite
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestSelector(t *testing.T) {
+	f := pbast.NewFile("openconfig.bgp.neighbors").
+		AddMessage(pbast.NewMessage("Neighbor").
+			AddMessage(pbast.NewMessage("Config")).
+			AddMessage(pbast.NewMessage("State"))).
+		AddMessage(pbast.NewMessage("Config"))
+
+	table := []struct {
+		selector Selector
+		expected []string
+	}{
+		{Selector{}, []string{"Neighbor", "Neighbor.Config", "Neighbor.State", "Config"}},
+		{Selector{Package: "openconfig.bgp.*"}, []string{"Neighbor", "Neighbor.Config", "Neighbor.State", "Config"}},
+		{Selector{Package: "openconfig.isis.*"}, nil},
+		{Selector{Message: regexp.MustCompile(`(^|\.)Config$`)}, []string{"Neighbor.Config", "Config"}},
+		{Selector{Package: "openconfig.*", Message: regexp.MustCompile(`^Neighbor\.`)}, []string{"Neighbor.Config", "Neighbor.State"}},
+	}
+
+	for x, d := range table {
+		names, _ := d.selector.Messages(f)
+		if !reflect.DeepEqual(names, d.expected) {
+			t.Errorf("#%d: got %v, want %v", x, names, d.expected)
+		}
+	}
+}
+
+func TestScoped(t *testing.T) {
+	renameKeywords, _ := LookupPass("rename-keywords")
+	p := &Pipeline{Passes: []Pass{Scoped(renameKeywords, Selector{Package: "org.*"})}}
+
+	for _, d := range []struct {
+		pkg      pbast.Package
+		expected string
+	}{
+		{"org.foo", "message_"},
+		{"com.foo", "message"},
+	} {
+		f, _, err := p.Run(pbast.NewFile(d.pkg).AddMessage(pbast.NewMessage("message")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual := f.Messages[0].Name; actual != d.expected {
+			t.Errorf("%s: got %s, want %s", d.pkg, actual, d.expected)
+		}
+	}
+
+	p = &Pipeline{Passes: []Pass{Scoped(renameKeywords, Selector{Message: regexp.MustCompile(`^Config$`)})}}
+	if _, _, err := p.Run(pbast.NewFile("org.foo")); err == nil {
+		t.Errorf("got no error for a message selector, want error")
+	}
+}
@@ -0,0 +1,134 @@
+package rewrite
+
+import (
+	"fmt"
+
+	"github.com/oshothebig/pbast"
+)
+
+// RemoveUnreachable removes messages and enums which are not reachable from
+// the services and the roots, the full names of messages or enums to keep.
+// Services are always kept, so they may be omitted from the roots.
+// A type is reachable when a reachable message or service refers to it,
+// or when a reachable type is nested in it. Types referred from extends are
+// always reachable. It returns the full names of the removed declarations.
+func RemoveUnreachable(f *pbast.File, roots ...string) ([]string, error) {
+	idx := newTypeIndex(f)
+
+	services := map[string]*pbast.Service{}
+	for _, s := range f.Services {
+		services[s.Name] = s
+	}
+
+	edges := map[string][]string{}
+	var queue []string
+	for _, r := range references(f) {
+		target, ok := idx.resolve(f.Package, r.scope, *r.name)
+		if !ok {
+			continue
+		}
+		edges[r.scope] = append(edges[r.scope], target)
+	}
+	for _, e := range f.Extends {
+		queue = append(queue, extendTargets(f, idx, "", e)...)
+	}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		name := qualify(scope, m.Name)
+		if scope != "" {
+			edges[name] = append(edges[name], scope)
+		}
+		for _, e := range m.Enums {
+			edges[qualify(name, e.Name)] = append(edges[qualify(name, e.Name)], name)
+		}
+		for _, e := range m.Extends {
+			queue = append(queue, extendTargets(f, idx, name, e)...)
+		}
+	})
+
+	for _, s := range f.Services {
+		queue = append(queue, rpcTargets(f, idx, s)...)
+	}
+	for _, root := range roots {
+		if _, ok := services[root]; ok {
+			continue
+		}
+		name, ok := idx.resolve(f.Package, "", root)
+		if !ok {
+			return nil, fmt.Errorf("root %s is not declared", root)
+		}
+		queue = append(queue, name)
+	}
+
+	reachable := map[string]bool{}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if reachable[v] {
+			continue
+		}
+		reachable[v] = true
+		queue = append(queue, edges[v]...)
+	}
+
+	var removed []string
+	f.Messages = removeMessages("", f.Messages, reachable, &removed)
+	f.Enums = removeEnums("", f.Enums, reachable, &removed)
+	return removed, nil
+}
+
+func extendTargets(f *pbast.File, idx typeIndex, scope string, e *pbast.Extend) []string {
+	var targets []string
+	names := []string{e.Type}
+	for _, field := range e.Fields {
+		names = append(names, field.Type)
+	}
+	for _, name := range names {
+		if target, ok := idx.resolve(f.Package, scope, name); ok {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+func rpcTargets(f *pbast.File, idx typeIndex, s *pbast.Service) []string {
+	var targets []string
+	for _, r := range s.RPCs {
+		for _, t := range []*pbast.ReturnType{r.Input, r.Output} {
+			if t == nil {
+				continue
+			}
+			if target, ok := idx.resolve(f.Package, "", t.Name); ok {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+func removeMessages(scope string, ms []*pbast.Message, reachable map[string]bool, removed *[]string) []*pbast.Message {
+	var kept []*pbast.Message
+	for _, m := range ms {
+		name := qualify(scope, m.Name)
+		if !reachable[name] {
+			*removed = append(*removed, name)
+			continue
+		}
+		m.Messages = removeMessages(name, m.Messages, reachable, removed)
+		m.Enums = removeEnums(name, m.Enums, reachable, removed)
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+func removeEnums(scope string, es []*pbast.Enum, reachable map[string]bool, removed *[]string) []*pbast.Enum {
+	var kept []*pbast.Enum
+	for _, e := range es {
+		name := qualify(scope, e.Name)
+		if !reachable[name] {
+			*removed = append(*removed, name)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
@@ -0,0 +1,93 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestRemoveUnreachable(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		roots    []string
+		expected *pbast.File
+		removed  []string
+	}{
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1)).
+					AddMessage(pbast.NewMessage("Unused"))).
+				AddMessage(pbast.NewMessage("B").
+					AddField(pbast.NewMessageField(pbast.NewEnum("E"), "e", 1))).
+				AddMessage(pbast.NewMessage("C")).
+				AddEnum(pbast.NewEnum("E")).
+				AddEnum(pbast.NewEnum("F")),
+			roots: []string{"A"},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1))).
+				AddMessage(pbast.NewMessage("B").
+					AddField(pbast.NewMessageField(pbast.NewEnum("E"), "e", 1))).
+				AddEnum(pbast.NewEnum("E")),
+			removed: []string{"A.Unused", "C", "F"},
+		},
+		// a nested root keeps the enclosing message and what it refers to,
+		// and services are kept with what they refer to without being roots
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1)).
+					AddMessage(pbast.NewMessage("Inner"))).
+				AddMessage(pbast.NewMessage("B")).
+				AddMessage(pbast.NewMessage("C")).
+				AddMessage(pbast.NewMessage("D")).
+				AddService(pbast.NewService("S").
+					AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("C"), pbast.NewReturnType("C")))).
+				AddService(pbast.NewService("T")),
+			roots: []string{"A.Inner"},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1)).
+					AddMessage(pbast.NewMessage("Inner"))).
+				AddMessage(pbast.NewMessage("B")).
+				AddMessage(pbast.NewMessage("C")).
+				AddService(pbast.NewService("S").
+					AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("C"), pbast.NewReturnType("C")))).
+				AddService(pbast.NewService("T")),
+			removed: []string{"D"},
+		},
+		// a type referred only from a group field is reachable
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddGroup(pbast.NewGroup("Result", 1).
+						AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 2)))).
+				AddMessage(pbast.NewMessage("B")),
+			roots: []string{"A"},
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddGroup(pbast.NewGroup("Result", 1).
+						AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 2)))).
+				AddMessage(pbast.NewMessage("B")),
+		},
+	}
+
+	for x, d := range table {
+		removed, err := RemoveUnreachable(d.in, d.roots...)
+		if err != nil {
+			t.Errorf("#%d: got %v, want no error", x, err)
+		}
+		if !reflect.DeepEqual(d.in, d.expected) {
+			t.Errorf("#%d: got %+v, want %+v", x, d.in, d.expected)
+		}
+		if !reflect.DeepEqual(removed, d.removed) {
+			t.Errorf("#%d: got %v, want %v", x, removed, d.removed)
+		}
+	}
+
+	if _, err := RemoveUnreachable(pbast.NewFile("org.foo"), "Missing"); err == nil {
+		t.Errorf("got no error for an undeclared root, want error")
+	}
+}
@@ -0,0 +1,116 @@
+package rewrite
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// SortMessages reorders messages in each scope so that a message is declared
+// after the messages it refers to wherever possible.
+// The declaration order is kept among independent messages, and
+// messages referring to each other are placed in the declaration order.
+func SortMessages(f *pbast.File) {
+	refs := resolveReferences(f)
+	f.Messages = sortSiblings("", f.Messages, refs)
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		m.Messages = sortSiblings(qualify(scope, m.Name), m.Messages, refs)
+	})
+}
+
+// sortSiblings sorts the messages declared in the scope by dependencies among them.
+// A message depends on another when something inside the former
+// refers to the latter or to something inside the latter.
+func sortSiblings(scope string, ms []*pbast.Message, refs []resolvedReference) []*pbast.Message {
+	if len(ms) < 2 {
+		return ms
+	}
+
+	index := map[string]int{}
+	for i, m := range ms {
+		index[m.Name] = i
+	}
+	// sibling returns the position of the sibling containing the full name
+	sibling := func(name string) (int, bool) {
+		if scope != "" {
+			if !strings.HasPrefix(name, scope+".") {
+				return 0, false
+			}
+			name = name[len(scope)+1:]
+		}
+		if i := strings.Index(name, "."); i >= 0 {
+			name = name[:i]
+		}
+		i, ok := index[name]
+		return i, ok
+	}
+
+	deps := make([]map[int]bool, len(ms))
+	for i := range deps {
+		deps[i] = map[int]bool{}
+	}
+	for _, r := range refs {
+		from, ok := sibling(r.scope)
+		if !ok {
+			continue
+		}
+		to, ok := sibling(r.target)
+		if !ok || from == to {
+			continue
+		}
+		deps[from][to] = true
+	}
+
+	placed := make([]bool, len(ms))
+	sorted := make([]*pbast.Message, 0, len(ms))
+	for len(sorted) < len(ms) {
+		next := -1
+		for i := range ms {
+			if !placed[i] && satisfied(deps[i], placed) {
+				next = i
+				break
+			}
+		}
+		// messages left depend on a cycle, so break it at the first message in a cycle
+		if next < 0 {
+			for i := range ms {
+				if !placed[i] && onCycle(i, deps, placed) {
+					next = i
+					break
+				}
+			}
+		}
+		placed[next] = true
+		sorted = append(sorted, ms[next])
+	}
+	return sorted
+}
+
+// onCycle returns whether the message can reach itself through unplaced dependencies
+func onCycle(start int, deps []map[int]bool, placed []bool) bool {
+	visited := map[int]bool{}
+	stack := []int{start}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for d := range deps[v] {
+			if d == start {
+				return true
+			}
+			if !placed[d] && !visited[d] {
+				visited[d] = true
+				stack = append(stack, d)
+			}
+		}
+	}
+	return false
+}
+
+func satisfied(deps map[int]bool, placed []bool) bool {
+	for d := range deps {
+		if !placed[d] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestSortMessages(t *testing.T) {
+	table := []struct {
+		in       *pbast.File
+		expected *pbast.File
+	}{
+		// already sorted
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A")).
+				AddMessage(pbast.NewMessage("B").
+					AddField(pbast.NewMessageField(pbast.NewMessage("A"), "a", 1))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A")).
+				AddMessage(pbast.NewMessage("B").
+					AddField(pbast.NewMessageField(pbast.NewMessage("A"), "a", 1))),
+		},
+		// forward reference, including one to a type nested in a sibling
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("C.Inner"), "c", 1))).
+				AddMessage(pbast.NewMessage("B")).
+				AddMessage(pbast.NewMessage("C").
+					AddMessage(pbast.NewMessage("Inner"))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("B")).
+				AddMessage(pbast.NewMessage("C").
+					AddMessage(pbast.NewMessage("Inner"))).
+				AddMessage(pbast.NewMessage("A").
+					AddField(pbast.NewMessageField(pbast.NewMessage("C.Inner"), "c", 1))),
+		},
+		// nested messages and a cycle
+		{
+			in: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Outer").
+					AddMessage(pbast.NewMessage("X").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Y"), "y", 1))).
+					AddMessage(pbast.NewMessage("Y").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Z"), "z", 1))).
+					AddMessage(pbast.NewMessage("Z").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Y"), "y", 1)))),
+			expected: pbast.NewFile("org.foo").
+				AddMessage(pbast.NewMessage("Outer").
+					AddMessage(pbast.NewMessage("Y").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Z"), "z", 1))).
+					AddMessage(pbast.NewMessage("X").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Y"), "y", 1))).
+					AddMessage(pbast.NewMessage("Z").
+						AddField(pbast.NewMessageField(pbast.NewMessage("Y"), "y", 1)))),
+		},
+	}
+
+	for x, d := range table {
+		SortMessages(d.in)
+		if !reflect.DeepEqual(d.in, d.expected) {
+			t.Errorf("#%d: got %+v, want %+v", x, d.in, d.expected)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package rewrite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/wkt"
+)
+
+// SplitFunc returns the name of the file where a top-level message, enum,
+// extend or service goes. It is called in the order of messages, enums,
+// extends and services, each in declaration order.
+type SplitFunc func(n pbast.Node) string
+
+// Split distributes the top-level declarations of a file into files named by fn.
+// The files share the package and the options of the original, and import each
+// other where a declaration refers to a type in another file. A file keeps the
+// public imports of the original and, of the others, the well-known type imports
+// it refers to and the imports which may declare the types it refers to outside
+// the original. Declarations in the results are shared with the original.
+// It returns an error when fn names no file for a declaration or when
+// the files would import each other in a cycle, which protoc rejects.
+func Split(f *pbast.File, fn SplitFunc) (map[string]*pbast.File, error) {
+	files := map[string]*pbast.File{}
+	// fileOf calls fn just once for each declaration
+	fileOf := func(n pbast.Node) (string, *pbast.File, error) {
+		name := fn(n)
+		if name == "" {
+			return "", nil, fmt.Errorf("no file for %s", declName(n))
+		}
+		if file, ok := files[name]; ok {
+			return name, file, nil
+		}
+		file := pbast.NewFile(f.Package)
+		file.Comment = f.Comment
+		file.Options = append(file.Options, f.Options...)
+		files[name] = file
+		return name, file, nil
+	}
+
+	// owners holds the file names keyed by top-level type names
+	owners := map[string]string{}
+	// deps holds the types referred from each file
+	deps := map[*pbast.File][]string{}
+	// external holds the names referred from each file which aren't declared in f
+	external := map[*pbast.File][]string{}
+	idx := newTypeIndex(f)
+	refer := func(file *pbast.File, scope string, names ...string) {
+		for _, name := range names {
+			if target, ok := idx.resolve(f.Package, scope, name); ok {
+				deps[file] = append(deps[file], topLevel(target))
+			} else if !keywords[name] {
+				external[file] = append(external[file], name)
+			}
+		}
+	}
+
+	for _, m := range f.Messages {
+		name, file, err := fileOf(m)
+		if err != nil {
+			return nil, err
+		}
+		owners[m.Name] = name
+		file.AddMessage(m)
+	}
+	for _, e := range f.Enums {
+		name, file, err := fileOf(e)
+		if err != nil {
+			return nil, err
+		}
+		owners[e.Name] = name
+		file.AddEnum(e)
+	}
+	for _, e := range f.Extends {
+		_, file, err := fileOf(e)
+		if err != nil {
+			return nil, err
+		}
+		file.AddExtend(e)
+		refer(file, "", e.Type)
+		for _, field := range e.Fields {
+			refer(file, "", field.Type)
+		}
+	}
+	for _, s := range f.Services {
+		_, file, err := fileOf(s)
+		if err != nil {
+			return nil, err
+		}
+		file.AddService(s)
+		for _, r := range s.RPCs {
+			for _, t := range []*pbast.ReturnType{r.Input, r.Output} {
+				if t != nil {
+					refer(file, "", t.Name)
+				}
+			}
+		}
+	}
+	for _, r := range references(f) {
+		if r.scope == "" {
+			continue
+		}
+		refer(files[owners[topLevel(r.scope)]], r.scope, *r.name)
+	}
+
+	imports := map[string][]string{}
+	for name, file := range files {
+		for _, i := range f.Imports {
+			if needsImport(i, external[file]) {
+				file.AddImport(i)
+			}
+		}
+
+		imported := map[string]bool{}
+		for _, dep := range deps[file] {
+			path := owners[dep]
+			if path == name || imported[path] {
+				continue
+			}
+			imported[path] = true
+			imports[name] = append(imports[name], path)
+		}
+		sort.Strings(imports[name])
+		for _, path := range imports[name] {
+			file.AddImport(pbast.NewImport(path))
+		}
+	}
+
+	if cycle := importCycle(imports); cycle != nil {
+		return nil, fmt.Errorf("files import each other: %s", strings.Join(cycle, " -> "))
+	}
+	return files, nil
+}
+
+// needsImport reports whether a file referring to the types not declared in
+// the original file needs the import of the original file.
+// Which import declares a type isn't known, so any import other than
+// the well-known types is kept for such a reference, qualified or not.
+func needsImport(i *pbast.Import, external []string) bool {
+	if i.Visibility == pbast.Public {
+		return true
+	}
+	// well-known types are imported only by the files referring to them
+	wellKnown := strings.HasPrefix(i.Name, "google/protobuf/")
+	for _, name := range external {
+		path, ok := wkt.ImportPath(name)
+		if ok && path == i.Name {
+			return true
+		}
+		if !ok && !wellKnown {
+			return true
+		}
+	}
+	return false
+}
+
+// importCycle returns a cycle of the file names in imports,
+// which holds the names of the files each file imports, or nil
+func importCycle(imports map[string][]string) []string {
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	done := map[string]bool{}
+	var path []string
+	onPath := map[string]bool{}
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		if onPath[name] {
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		}
+		if done[name] {
+			return nil
+		}
+		path = append(path, name)
+		onPath[name] = true
+		for _, next := range imports[name] {
+			if cycle := visit(next); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[name] = false
+		done[name] = true
+		return nil
+	}
+
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func topLevel(name string) string {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func declName(n pbast.Node) string {
+	switch n := n.(type) {
+	case *pbast.Message:
+		return n.Name
+	case *pbast.Enum:
+		return n.Name
+	case *pbast.Service:
+		return n.Name
+	case *pbast.Extend:
+		return n.Type
+	default:
+		return ""
+	}
+}
+
+// SplitByDeclaration puts each top-level declaration into its own file
+// named by name from the declaration name
+func SplitByDeclaration(name func(decl string) string) SplitFunc {
+	return func(n pbast.Node) string {
+		return name(declName(n))
+	}
+}
+
+// SplitByPrefix puts a declaration whose name starts with a key of files
+// into the file of the value, preferring the longest prefix.
+// Declarations matching no prefix go to the rest.
+func SplitByPrefix(files map[string]string, rest string) SplitFunc {
+	prefixes := make([]string, 0, len(files))
+	for p := range files {
+		prefixes = append(prefixes, p)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	return func(n pbast.Node) string {
+		name := declName(n)
+		for _, p := range prefixes {
+			if strings.HasPrefix(name, p) {
+				return files[p]
+			}
+		}
+		return rest
+	}
+}
+
+// SplitByCount puts up to max declarations into each file.
+// The files are named by name with sequential numbers starting from 0.
+// With max less than 1 it names no file, which Split reports as an error.
+func SplitByCount(max int, name func(i int) string) SplitFunc {
+	count := 0
+	return func(n pbast.Node) string {
+		if max < 1 {
+			return ""
+		}
+		i := count / max
+		count++
+		return name(i)
+	}
+}
+
+// NumberedFileName returns a function naming files as "<base>_<i>.proto"
+func NumberedFileName(base string) func(i int) string {
+	return func(i int) string {
+		return fmt.Sprintf("%s_%d.proto", base, i)
+	}
+}
@@ -0,0 +1,146 @@
+package rewrite
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func newSplitTestFile() *pbast.File {
+	return pbast.NewFile("org.foo").
+		AddImport(pbast.NewImport("common.proto")).
+		AddImport(pbast.NewImport("google/protobuf/timestamp.proto")).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("InterfaceConfig"), "config", 1))).
+		AddMessage(pbast.NewMessage("InterfaceConfig").
+			AddField(pbast.NewMessageField(pbast.NewEnum("Status"), "status", 1)).
+			AddField(pbast.NewMessageField(pbast.NewMessage("common.Counters"), "counters", 2))).
+		AddMessage(pbast.NewMessage("Vlan").
+			AddField(pbast.NewMessageField(pbast.Timestamp, "created", 1))).
+		AddEnum(pbast.NewEnum("Status")).
+		AddService(pbast.NewService("Interfaces").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("Vlan"), pbast.NewReturnType("Interface"))))
+}
+
+func TestSplitByDeclaration(t *testing.T) {
+	files, err := Split(newSplitTestFile(), SplitByDeclaration(func(name string) string {
+		return strings.ToLower(name) + ".proto"
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"interface.proto":       {"interfaceconfig.proto"},
+		"interfaceconfig.proto": {"common.proto", "status.proto"},
+		"vlan.proto":            {"google/protobuf/timestamp.proto"},
+		"status.proto":          nil,
+		"interfaces.proto":      {"interface.proto", "vlan.proto"},
+	}
+	if len(files) != len(expected) {
+		t.Errorf("got %d files, want %d", len(files), len(expected))
+	}
+	for name, imports := range expected {
+		var actual []string
+		for _, i := range files[name].Imports {
+			actual = append(actual, i.Name)
+		}
+		if !reflect.DeepEqual(actual, imports) {
+			t.Errorf("%s: got %v, want %v", name, actual, imports)
+		}
+	}
+}
+
+func TestSplitImports(t *testing.T) {
+	// Common is declared in the same package by the imported file
+	f := pbast.NewFile("org.foo").
+		AddImport(pbast.NewImport("org/foo/common.proto")).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("Common"), "common", 1))).
+		AddMessage(pbast.NewMessage("Vlan").
+			AddField(pbast.NewMessageField(pbast.UInt32, "id", 1)))
+	files, err := Split(f, SplitByDeclaration(func(name string) string {
+		return strings.ToLower(name) + ".proto"
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"interface.proto": {"org/foo/common.proto"},
+		"vlan.proto":      nil,
+	}
+	for name, imports := range expected {
+		var actual []string
+		for _, i := range files[name].Imports {
+			actual = append(actual, i.Name)
+		}
+		if !reflect.DeepEqual(actual, imports) {
+			t.Errorf("%s: got %v, want %v", name, actual, imports)
+		}
+	}
+}
+
+func TestSplitByPrefix(t *testing.T) {
+	files, err := Split(newSplitTestFile(), SplitByPrefix(map[string]string{
+		"Interface":       "interface.proto",
+		"InterfaceConfig": "config.proto",
+	}, "rest.proto"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := pbast.NewFile("org.foo").
+		AddImport(pbast.NewImport("config.proto")).
+		AddImport(pbast.NewImport("rest.proto")).
+		AddMessage(pbast.NewMessage("Interface").
+			AddField(pbast.NewMessageField(pbast.NewMessage("InterfaceConfig"), "config", 1))).
+		AddService(pbast.NewService("Interfaces").
+			AddRPC(pbast.NewRPC("Get", pbast.NewReturnType("Vlan"), pbast.NewReturnType("Interface"))))
+
+	if !reflect.DeepEqual(files["interface.proto"], expected) {
+		t.Errorf("got %+v, want %+v", files["interface.proto"], expected)
+	}
+}
+
+func TestSplitByCount(t *testing.T) {
+	files, err := Split(newSplitTestFile(), SplitByCount(2, NumberedFileName("part")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]int{
+		"part_0.proto": 2,
+		"part_1.proto": 2,
+		"part_2.proto": 1,
+	}
+	if len(files) != len(expected) {
+		t.Errorf("got %d files, want %d", len(files), len(expected))
+	}
+	for name, count := range expected {
+		f := files[name]
+		if actual := len(f.Messages) + len(f.Enums) + len(f.Services); actual != count {
+			t.Errorf("%s: got %d declarations, want %d", name, actual, count)
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	if _, err := Split(newSplitTestFile(), SplitByCount(0, NumberedFileName("part"))); err == nil {
+		t.Errorf("got no error for max 0, want error")
+	}
+
+	f := pbast.NewFile("org.foo").
+		AddMessage(pbast.NewMessage("A").
+			AddField(pbast.NewMessageField(pbast.NewMessage("B"), "b", 1))).
+		AddMessage(pbast.NewMessage("B").
+			AddField(pbast.NewMessageField(pbast.NewMessage("A"), "a", 1)))
+	_, err := Split(f, SplitByDeclaration(func(name string) string {
+		return strings.ToLower(name) + ".proto"
+	}))
+	if expected := "files import each other: a.proto -> b.proto -> a.proto"; err == nil || err.Error() != expected {
+		t.Errorf("got %v, want %q", err, expected)
+	}
+}
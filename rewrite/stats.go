@@ -0,0 +1,114 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+// Stats summarizes the shape of files to guide which passes are worth enabling
+type Stats struct {
+	Files    int
+	Messages int
+	Enums    int
+	Fields   int
+	// FieldsPerMessage maps numbers of fields, including those in oneofs,
+	// to the numbers of messages having that many fields
+	FieldsPerMessage map[int]int
+	// NestingDepth maps nesting depths to the numbers of messages declared
+	// at the depths. Top-level messages are at depth 0.
+	NestingDepth map[int]int
+	// EnumSizes maps numbers of values to the numbers of enums having that many values
+	EnumSizes map[int]int
+	// Duplicates maps the names of nested messages declared more than once in
+	// a file, all of the same structure referring to the same types, which
+	// LiftMessages can merge, to the numbers of copies
+	Duplicates map[string]int
+}
+
+// Analyze returns the statistics of the files
+func Analyze(files ...*pbast.File) Stats {
+	s := Stats{
+		Files:            len(files),
+		FieldsPerMessage: map[int]int{},
+		NestingDepth:     map[int]int{},
+		EnumSizes:        map[int]int{},
+		Duplicates:       map[string]int{},
+	}
+	enum := func(e *pbast.Enum) {
+		s.Enums++
+		s.EnumSizes[len(e.Fields)]++
+	}
+
+	for _, f := range files {
+		// copies holds the full names of the nested messages keyed by their names
+		copies := map[string][]string{}
+		for _, e := range f.Enums {
+			enum(e)
+		}
+		pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+			fields := len(m.Fields) + len(m.Maps) + len(m.Groups)
+			for _, o := range m.OneOfs {
+				fields += len(o.Fields)
+			}
+			s.Messages++
+			s.Fields += fields
+			s.FieldsPerMessage[fields]++
+			if scope == "" {
+				s.NestingDepth[0]++
+			} else {
+				s.NestingDepth[strings.Count(scope, ".")+1]++
+			}
+			for _, e := range m.Enums {
+				enum(e)
+			}
+
+			if scope != "" {
+				copies[m.Name] = append(copies[m.Name], qualify(scope, m.Name))
+			}
+		})
+
+		idx := newTypeIndex(f)
+		refs := resolveReferences(f)
+		for name, cs := range copies {
+			if len(cs) < 2 || nested(cs) || !sameMessages(idx, refs, cs) {
+				continue
+			}
+			s.Duplicates[name] += len(cs)
+		}
+	}
+	return s
+}
+
+func (s Stats) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "files: %d, messages: %d, enums: %d, fields: %d\n", s.Files, s.Messages, s.Enums, s.Fields)
+	histogram := func(title string, h map[int]int) {
+		keys := make([]int, 0, len(h))
+		for k := range h {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %d: %d\n", k, h[k])
+		}
+	}
+	histogram("fields per message", s.FieldsPerMessage)
+	histogram("nesting depth", s.NestingDepth)
+	histogram("enum sizes", s.EnumSizes)
+
+	names := make([]string, 0, len(s.Duplicates))
+	for name := range s.Duplicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(&b, "duplicated messages:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s: %d\n", name, s.Duplicates[name])
+	}
+	return b.String()
+}
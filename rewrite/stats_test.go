@@ -0,0 +1,43 @@
+package rewrite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+	"github.com/oshothebig/pbast/pbasttest"
+)
+
+func TestAnalyze(t *testing.T) {
+	other := pbast.NewFile("org.bar").
+		AddMessage(pbast.NewMessage("Empty")).
+		AddEnum(pbast.NewEnum("Kind").
+			AddField(pbast.NewEnumField("KIND_UNKNOWN", 0)))
+
+	expected := Stats{
+		Files:    2,
+		Messages: 16,
+		Enums:    2,
+		Fields:   54,
+		FieldsPerMessage: map[int]int{
+			0: 1,
+			2: 3,
+			3: 3,
+			4: 6,
+			5: 3,
+		},
+		NestingDepth: map[int]int{0: 4, 1: 9, 2: 3},
+		EnumSizes:    map[int]int{1: 1, 3: 1},
+		// Entry isn't counted as the copies refer to different Configs
+		Duplicates: map[string]int{
+			"Config":   3,
+			"State":    3,
+			"Counters": 3,
+		},
+	}
+
+	actual := Analyze(pbasttest.NewModel(3), other)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got\n%v\nwant\n%v", actual, expected)
+	}
+}
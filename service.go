@@ -1,10 +1,10 @@
 package pbast
 
 type Service struct {
-	Name    string
-	Comment Comment
-	Options []*Option
-	RPCs    []*RPC
+	Name    string    `json:"name"`
+	Comment Comment   `json:"comment,omitempty"`
+	Options []*Option `json:"options,omitempty"`
+	RPCs    []*RPC    `json:"rpcs,omitempty"`
 }
 
 func NewService(name string) *Service {
@@ -30,11 +30,12 @@ func (s *Service) AddRPC(r *RPC) *Service {
 }
 
 type RPC struct {
-	Name    string
-	Comment Comment
-	Input   *ReturnType
-	Output  *ReturnType
-	Options []*Option
+	Name             string           `json:"name"`
+	Comment          Comment          `json:"comment,omitempty"`
+	Input            *ReturnType      `json:"input"`
+	Output           *ReturnType      `json:"output"`
+	IdempotencyLevel IdempotencyLevel `json:"idempotencyLevel,omitempty"`
+	Options          []*Option        `json:"options,omitempty"`
 }
 
 func NewRPC(name string, input *ReturnType, output *ReturnType) *RPC {
@@ -53,9 +54,45 @@ func (r *RPC) AddOption(o *Option) *RPC {
 	return r
 }
 
+// SetClientStreaming sets whether the client streams the input
+func (r *RPC) SetClientStreaming(s bool) *RPC {
+	r.Input.SetStreamable(s)
+	return r
+}
+
+// SetServerStreaming sets whether the server streams the output
+func (r *RPC) SetServerStreaming(s bool) *RPC {
+	r.Output.SetStreamable(s)
+	return r
+}
+
+func (r *RPC) SetIdempotencyLevel(l IdempotencyLevel) *RPC {
+	r.IdempotencyLevel = l
+	return r
+}
+
+type IdempotencyLevel int
+
+const (
+	IdempotencyUnknown IdempotencyLevel = iota
+	NoSideEffects
+	Idempotent
+)
+
+func (l IdempotencyLevel) String() string {
+	switch l {
+	case NoSideEffects:
+		return "NO_SIDE_EFFECTS"
+	case Idempotent:
+		return "IDEMPOTENT"
+	default:
+		return "IDEMPOTENCY_UNKNOWN"
+	}
+}
+
 type ReturnType struct {
-	Name       string
-	Streamable bool
+	Name       string `json:"name"`
+	Streamable bool   `json:"streamable,omitempty"`
 }
 
 func NewReturnType(name string) *ReturnType {
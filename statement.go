@@ -7,8 +7,8 @@ func (Syntax) String() string {
 }
 
 type Import struct {
-	Name       string
-	Visibility Visibility
+	Name       string     `json:"name"`
+	Visibility Visibility `json:"visibility,omitempty"`
 }
 
 func NewImport(name string) *Import {
@@ -57,9 +57,9 @@ func NewPackage(name string) Package {
 }
 
 type Option struct {
-	Name string
+	Name string `json:"name"`
 	// TODO: Revisit for type safety
-	Value string
+	Value string `json:"value"`
 }
 
 func NewOption(name, value string) *Option {
@@ -70,9 +70,10 @@ func NewOption(name, value string) *Option {
 }
 
 type OneOf struct {
-	Name    string
-	Comment Comment
-	Fields  []*OneOfField
+	Name    string        `json:"name"`
+	Comment Comment       `json:"comment,omitempty"`
+	Options []*Option     `json:"options,omitempty"`
+	Fields  []*OneOfField `json:"fields,omitempty"`
 }
 
 func NewOneOf(name string) *OneOf {
@@ -81,6 +82,14 @@ func NewOneOf(name string) *OneOf {
 	}
 }
 
+func (o *OneOf) AddOption(opt *Option) *OneOf {
+	if opt == nil {
+		return o
+	}
+	o.Options = append(o.Options, opt)
+	return o
+}
+
 func (o *OneOf) AddField(f *OneOfField) *OneOf {
 	if f == nil {
 		return o
@@ -90,11 +99,11 @@ func (o *OneOf) AddField(f *OneOfField) *OneOf {
 }
 
 type OneOfField struct {
-	Type    string
-	Name    string
-	Index   int
-	Comment Comment
-	Options []*Option
+	Type    string    `json:"type"`
+	Name    string    `json:"name"`
+	Index   int       `json:"index"`
+	Comment Comment   `json:"comment,omitempty"`
+	Options []*Option `json:"options,omitempty"`
 }
 
 func NewOneOfField(t Type, name string, index int) *OneOfField {
@@ -72,6 +72,18 @@ const (
 // Define well know type for "Type" when coming up with how to resolve naming conflict
 )
 
+// Options defined in google/protobuf/descriptor.proto, which are extended to define custom options
+const (
+	FileOptions      WellKnownType = "google.protobuf.FileOptions"
+	MessageOptions   WellKnownType = "google.protobuf.MessageOptions"
+	FieldOptions     WellKnownType = "google.protobuf.FieldOptions"
+	OneofOptions     WellKnownType = "google.protobuf.OneofOptions"
+	EnumOptions      WellKnownType = "google.protobuf.EnumOptions"
+	EnumValueOptions WellKnownType = "google.protobuf.EnumValueOptions"
+	ServiceOptions   WellKnownType = "google.protobuf.ServiceOptions"
+	MethodOptions    WellKnownType = "google.protobuf.MethodOptions"
+)
+
 func (e Enum) TypeName() string {
 	return e.Name
 }
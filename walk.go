@@ -0,0 +1,59 @@
+package pbast
+
+// WalkMessages calls fn for every message in the file including nested ones
+// in declaration order, parents before the messages nested in them.
+// scope is the full name, without the package, of the message declaring m,
+// or "" for a top level message. It is computed after fn returns,
+// so a message renamed by fn is seen with its new name by the nested ones.
+// A message nested in itself is visited only once not to loop infinitely.
+func WalkMessages(f *File, fn func(scope string, m *Message)) {
+	w := &messageWalker{visit: fn}
+	w.walkFile(f)
+}
+
+// SelfNestedMessages returns the full names, without the package, of the
+// places where a message is nested in itself directly or indirectly.
+// Such a structure can't be represented in a .proto file.
+func SelfNestedMessages(f *File) []string {
+	var names []string
+	w := &messageWalker{
+		visit: func(string, *Message) {},
+		nested: func(name string) {
+			names = append(names, name)
+		},
+	}
+	w.walkFile(f)
+	return names
+}
+
+type messageWalker struct {
+	visit func(scope string, m *Message)
+	// nested is called with the full name of a message found nested in itself
+	nested func(name string)
+	// ancestors holds the messages being walked
+	ancestors map[*Message]bool
+}
+
+func (w *messageWalker) walkFile(f *File) {
+	w.ancestors = map[*Message]bool{}
+	for _, m := range f.Messages {
+		w.walk("", m)
+	}
+}
+
+func (w *messageWalker) walk(scope string, m *Message) {
+	if w.ancestors[m] {
+		if w.nested != nil {
+			w.nested(qualify(scope, m.Name))
+		}
+		return
+	}
+	w.ancestors[m] = true
+	defer delete(w.ancestors, m)
+
+	w.visit(scope, m)
+	name := qualify(scope, m.Name)
+	for _, n := range m.Messages {
+		w.walk(name, n)
+	}
+}
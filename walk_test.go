@@ -0,0 +1,30 @@
+package pbast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalkMessages(t *testing.T) {
+	node := NewMessage("Node")
+	node.AddMessage(NewMessage("Leaf").AddMessage(node))
+
+	f := NewFile("org.foo").
+		AddMessage(NewMessage("Outer").
+			AddMessage(NewMessage("Inner"))).
+		AddMessage(node)
+
+	var visited []string
+	WalkMessages(f, func(scope string, m *Message) {
+		visited = append(visited, qualify(scope, m.Name))
+	})
+	expected := []string{"Outer", "Outer.Inner", "Node", "Node.Leaf"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("got %q, want %q", visited, expected)
+	}
+
+	nested := SelfNestedMessages(f)
+	if !reflect.DeepEqual(nested, []string{"Node.Leaf.Node"}) {
+		t.Errorf("got %q, want %q", nested, []string{"Node.Leaf.Node"})
+	}
+}
@@ -0,0 +1,157 @@
+package wkt
+
+import (
+	"strings"
+
+	"github.com/oshothebig/pbast"
+)
+
+const (
+	Any       = pbast.Any
+	Duration  = pbast.Duration
+	Empty     = pbast.Empty
+	FieldMask = pbast.FieldMask
+	Timestamp = pbast.Timestamp
+
+	Struct    = pbast.WellKnownStruct
+	Value     = pbast.Value
+	ListValue = pbast.ListValue
+	NullValue = pbast.NullValue
+
+	DoubleValue = pbast.DoubleValue
+	FloatValue  = pbast.FloatValue
+	Int64Value  = pbast.Int64Value
+	UInt64Value = pbast.UInt64Value
+	Int32Value  = pbast.Int32Value
+	UInt32Value = pbast.UInt32Value
+	BoolValue   = pbast.BoolValue
+	StringValue = pbast.StringValue
+	BytesValue  = pbast.BytesValue
+)
+
+var importPaths = map[pbast.WellKnownType]string{
+	pbast.Any:              "google/protobuf/any.proto",
+	pbast.Api:              "google/protobuf/api.proto",
+	pbast.Method:           "google/protobuf/api.proto",
+	pbast.Mixin:            "google/protobuf/api.proto",
+	pbast.Duration:         "google/protobuf/duration.proto",
+	pbast.Empty:            "google/protobuf/empty.proto",
+	pbast.FieldMask:        "google/protobuf/field_mask.proto",
+	pbast.SourceContext:    "google/protobuf/source_context.proto",
+	pbast.WellKnownStruct:  "google/protobuf/struct.proto",
+	pbast.Value:            "google/protobuf/struct.proto",
+	pbast.ListValue:        "google/protobuf/struct.proto",
+	pbast.NullValue:        "google/protobuf/struct.proto",
+	pbast.Timestamp:        "google/protobuf/timestamp.proto",
+	pbast.WellKnownEnum:    "google/protobuf/type.proto",
+	pbast.EnumValue:        "google/protobuf/type.proto",
+	pbast.WellKnownField:   "google/protobuf/type.proto",
+	pbast.Cardinality:      "google/protobuf/type.proto",
+	pbast.Kind:             "google/protobuf/type.proto",
+	pbast.WellKnownOption:  "google/protobuf/type.proto",
+	pbast.WellKnownSyntax:  "google/protobuf/type.proto",
+	pbast.DoubleValue:      "google/protobuf/wrappers.proto",
+	pbast.FloatValue:       "google/protobuf/wrappers.proto",
+	pbast.Int64Value:       "google/protobuf/wrappers.proto",
+	pbast.UInt64Value:      "google/protobuf/wrappers.proto",
+	pbast.Int32Value:       "google/protobuf/wrappers.proto",
+	pbast.UInt32Value:      "google/protobuf/wrappers.proto",
+	pbast.BoolValue:        "google/protobuf/wrappers.proto",
+	pbast.StringValue:      "google/protobuf/wrappers.proto",
+	pbast.BytesValue:       "google/protobuf/wrappers.proto",
+	pbast.FileOptions:      "google/protobuf/descriptor.proto",
+	pbast.MessageOptions:   "google/protobuf/descriptor.proto",
+	pbast.FieldOptions:     "google/protobuf/descriptor.proto",
+	pbast.OneofOptions:     "google/protobuf/descriptor.proto",
+	pbast.EnumOptions:      "google/protobuf/descriptor.proto",
+	pbast.EnumValueOptions: "google/protobuf/descriptor.proto",
+	pbast.ServiceOptions:   "google/protobuf/descriptor.proto",
+	pbast.MethodOptions:    "google/protobuf/descriptor.proto",
+}
+
+// ImportPath returns the path of the file defining the well-known type
+// referred by the type name
+func ImportPath(name string) (string, bool) {
+	path, ok := importPaths[pbast.WellKnownType(strings.TrimPrefix(name, "."))]
+	return path, ok
+}
+
+// Import adds an import of the file defining the well-known type
+// unless the file is already imported
+func Import(f *pbast.File, t pbast.Type) *pbast.File {
+	path, ok := ImportPath(t.TypeName())
+	if !ok {
+		return f
+	}
+
+	for _, i := range f.Imports {
+		if i.Name == path {
+			return f
+		}
+	}
+	return f.AddImport(pbast.NewImport(path))
+}
+
+// NewField returns a field of the type and imports the file defining the type into f
+func NewField(f *pbast.File, t pbast.Type, name string, index int) *pbast.MessageField {
+	Import(f, t)
+	return pbast.NewMessageField(t, name, index)
+}
+
+// NewRepeatedField returns a repeated field of the type and imports the file defining the type into f
+func NewRepeatedField(f *pbast.File, t pbast.Type, name string, index int) *pbast.MessageField {
+	Import(f, t)
+	return pbast.NewRepeatedMessageField(t, name, index)
+}
+
+// AddImports imports the files defining the well-known types referred
+// from fields, extends and RPCs in the file
+func AddImports(f *pbast.File) *pbast.File {
+	for _, name := range referredTypes(f) {
+		Import(f, pbast.WellKnownType(strings.TrimPrefix(name, ".")))
+	}
+	return f
+}
+
+func referredTypes(f *pbast.File) []string {
+	var names []string
+	extends := func(es []*pbast.Extend) {
+		for _, e := range es {
+			names = append(names, e.Type)
+			for _, field := range e.Fields {
+				names = append(names, field.Type)
+			}
+		}
+	}
+	pbast.WalkMessages(f, func(scope string, m *pbast.Message) {
+		for _, field := range m.Fields {
+			names = append(names, field.Type)
+		}
+		for _, field := range m.Maps {
+			names = append(names, field.ValueType)
+		}
+		for _, o := range m.OneOfs {
+			for _, field := range o.Fields {
+				names = append(names, field.Type)
+			}
+		}
+		for _, g := range m.Groups {
+			for _, field := range g.Fields {
+				names = append(names, field.Type)
+			}
+		}
+		extends(m.Extends)
+	})
+	extends(f.Extends)
+	for _, s := range f.Services {
+		for _, r := range s.RPCs {
+			if r.Input != nil {
+				names = append(names, r.Input.Name)
+			}
+			if r.Output != nil {
+				names = append(names, r.Output.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,52 @@
+package wkt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oshothebig/pbast"
+)
+
+func TestNewField(t *testing.T) {
+	f := pbast.NewFile("org.foo")
+	f.AddMessage(pbast.NewMessage("Human").
+		AddField(NewField(f, Timestamp, "born_at", 1)).
+		AddField(NewField(f, Timestamp, "updated_at", 2)).
+		AddField(NewRepeatedField(f, StringValue, "nicknames", 3)).
+		AddField(NewField(f, pbast.String, "name", 4)))
+
+	expected := []*pbast.Import{
+		pbast.NewImport("google/protobuf/timestamp.proto"),
+		pbast.NewImport("google/protobuf/wrappers.proto"),
+	}
+	if !reflect.DeepEqual(f.Imports, expected) {
+		t.Errorf("got %+v, want %+v", f.Imports, expected)
+	}
+}
+
+func TestAddImports(t *testing.T) {
+	f := pbast.NewFile("org.foo").
+		AddImport(pbast.NewImport("google/protobuf/any.proto")).
+		AddMessage(pbast.NewMessage("Human").
+			AddField(pbast.NewMessageField(Any, "detail", 1)).
+			AddMapField(pbast.NewMapField(pbast.String, Struct, "attributes", 2)).
+			AddMessage(pbast.NewMessage("Inner").
+				AddField(pbast.NewMessageField(pbast.WellKnownType(".google.protobuf.Duration"), "age", 1)))).
+		AddExtend(pbast.NewExtend(pbast.FieldOptions).
+			AddField(pbast.NewMessageField(pbast.String, "unit", 50000))).
+		AddService(pbast.NewService("Humans").
+			AddRPC(pbast.NewRPC("List", pbast.NewReturnType(Empty.TypeName()), pbast.NewReturnType("Human"))))
+
+	AddImports(f)
+
+	expected := []*pbast.Import{
+		pbast.NewImport("google/protobuf/any.proto"),
+		pbast.NewImport("google/protobuf/struct.proto"),
+		pbast.NewImport("google/protobuf/duration.proto"),
+		pbast.NewImport("google/protobuf/descriptor.proto"),
+		pbast.NewImport("google/protobuf/empty.proto"),
+	}
+	if !reflect.DeepEqual(f.Imports, expected) {
+		t.Errorf("got %+v, want %+v", f.Imports, expected)
+	}
+}